@@ -15,7 +15,6 @@ require (
 	github.com/goccy/go-json v0.10.5
 	github.com/gofiber/contrib/websocket v1.3.4
 	github.com/gofiber/fiber/v2 v2.52.8
-	github.com/muka/peerjs-go v0.0.0-20240401061429-5b28944b9e4f
 	github.com/oklog/ulid/v2 v2.1.1
 	github.com/pion/webrtc/v3 v3.3.5
 	github.com/valyala/fasthttp v1.62.0
@@ -24,7 +23,6 @@ require (
 
 require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
-	github.com/chuckpreslar/emission v0.0.0-20170206194824-a7ddd980baf9 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fasthttp/websocket v1.5.12 // indirect
 	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
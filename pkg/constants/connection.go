@@ -0,0 +1,8 @@
+package constants
+
+// Peer-to-peer connection outcomes, as reported by clients
+const (
+	CONNECTION_CONNECTED string = "connected" // The peers are connected directly.
+	CONNECTION_FAILED    string = "failed"    // The connection could not be established.
+	CONNECTION_TIMED_OUT string = "timed_out" // The connection was not established in time.
+)
@@ -0,0 +1,82 @@
+package constants
+
+import "time"
+
+// Chat limits
+const (
+	CHAT_MAX_LENGTH  int           = 500             // Maximum size of a single chat message, in bytes.
+	CHAT_RATE_LIMIT  int           = 5               // Maximum number of chat messages a client may send within CHAT_RATE_WINDOW.
+	CHAT_RATE_WINDOW time.Duration = 5 * time.Second // Sliding window used for chat rate limiting.
+)
+
+// Match start countdown
+const (
+	COUNTDOWN_DEFAULT time.Duration = 5 * time.Second        // Countdown length used when the host doesn't specify one.
+	COUNTDOWN_MAX     time.Duration = 60 * time.Second       // Longest countdown the host may request.
+	MATCH_START_LEAD  time.Duration = 500 * time.Millisecond // MATCH_START is sent this long before the start time so peers can schedule it.
+)
+
+// Heartbeats and latency measurement
+const (
+	PING_INTERVAL    time.Duration = 10 * time.Second // How often the server pings each client to measure its round-trip time.
+	HEARTBEAT_MISSES int           = 3                // Number of ping intervals a client may stay silent before it is disconnected.
+)
+
+// Lobby expiry
+const (
+	JANITOR_INTERVAL     time.Duration = 15 * time.Second // How often lobbies are checked for expiry.
+	LOBBY_EXPIRY_WARNING time.Duration = time.Minute      // Default for how long before expiry the host is warned.
+)
+
+// Invites
+const (
+	INVITE_CODE_LENGTH int = 8  // Number of characters in an invite code.
+	MAX_INVITES        int = 16 // Maximum number of active invite codes per lobby.
+)
+
+// Join requests
+const (
+	JOIN_REQUEST_TIMEOUT time.Duration = time.Minute // How long the host has to answer a join request before it is denied.
+)
+
+// Reservations
+const (
+	RESERVATION_DEFAULT time.Duration = 2 * time.Minute  // How long a reserved slot is held if the host doesn't say.
+	RESERVATION_MAX     time.Duration = 10 * time.Minute // Longest a reserved slot may be held.
+)
+
+// Relay limits
+const (
+	MAX_RELAYS          int           = 256              // Default maximum number of relays on the server.
+	MAX_RELAYS_PER_GAME int           = 16               // Default maximum number of relays per game.
+	MAX_RELAY_PEERS     int           = 32               // Default maximum number of peers connected to a single relay.
+	RELAY_BYTES_PER_SEC int64         = 1 << 20          // Default number of bytes a single relay may forward per second.
+	RELAY_TICKET_TTL    time.Duration = 10 * time.Minute // How long a relay ticket can be used to connect to a relay.
+)
+
+// Lobby variables
+const (
+	MAX_LOBBY_VARIABLES int = 64   // Maximum number of variables in a single lobby.
+	MAX_VARIABLE_KEY    int = 64   // Maximum length of a variable key.
+	MAX_VARIABLE_SIZE   int = 4096 // Maximum size of a variable's JSON-encoded value in bytes.
+)
+
+// Shared seeds
+const (
+	SEED_COMMIT_TIMEOUT time.Duration = 10 * time.Second // How long participants have to commit to a secret.
+	SEED_REVEAL_TIMEOUT time.Duration = 10 * time.Second // How long participants have to reveal their secret.
+	SEED_SECRET_MIN     int           = 16               // Minimum size of a secret in bytes.
+	SEED_SECRET_MAX     int           = 64               // Maximum size of a secret in bytes.
+	SEED_MAX_STRIKES    int           = 3                // Number of missed reveals after which a player is removed from the lobby.
+)
+
+// Parties
+const (
+	MAX_PARTY_SIZE int = 8 // Maximum number of clients in a single party, including the leader.
+)
+
+// Team limits
+const (
+	MAX_TEAMS     int   = 16 // Maximum number of teams in a single lobby.
+	MAX_TEAM_SIZE int64 = 64 // Maximum number of slots in a single team.
+)
@@ -0,0 +1,16 @@
+package constants
+
+// Lobby visibility modes
+const (
+	VISIBILITY_PUBLIC      string = "public"      // The lobby is listed and can be joined by name (default).
+	VISIBILITY_UNLISTED    string = "unlisted"    // The lobby is not listed, but can be found and joined by name.
+	VISIBILITY_INVITE_ONLY string = "invite_only" // The lobby is not listed and can only be joined with an invite code.
+)
+
+// Lobby phases
+const (
+	PHASE_WAITING     string = "waiting"     // Players are gathering (default).
+	PHASE_STARTING    string = "starting"    // The match start countdown is running.
+	PHASE_IN_PROGRESS string = "in_progress" // The match is being played.
+	PHASE_FINISHED    string = "finished"    // The match is over.
+)
@@ -0,0 +1,7 @@
+package constants
+
+// Who a match result was reported by
+const (
+	RESULT_BY_HOST   string = "host"   // The lobby host submitted the result.
+	RESULT_BY_QUORUM string = "quorum" // A majority of the players submitted the same result.
+)
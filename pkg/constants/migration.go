@@ -0,0 +1,10 @@
+package constants
+
+// Host migration policies
+const (
+	MIGRATE_JOIN_ORDER     string = "join_order"     // The member that joined first becomes the host (default).
+	MIGRATE_LOWEST_LATENCY string = "lowest_latency" // The member with the lowest latency becomes the host.
+	MIGRATE_HIGHEST_UPTIME string = "highest_uptime" // The member that has been connected the longest becomes the host.
+	MIGRATE_SUCCESSORS     string = "successors"     // The first member on the host's successor list becomes the host, falling back to join order.
+	MIGRATE_CLOSE          string = "close"          // The lobby is closed when the host leaves.
+)
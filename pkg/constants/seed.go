@@ -0,0 +1,7 @@
+package constants
+
+// Shared seed round phases
+const (
+	SEED_PHASE_COMMIT string = "commit" // Participants send a hash of their secret.
+	SEED_PHASE_REVEAL string = "reveal" // Participants send the secret itself.
+)
@@ -0,0 +1,8 @@
+package constants
+
+// Lobby variable permissions
+const (
+	VAR_ANYONE     string = "anyone" // Any player may change the variable (default).
+	VAR_HOST_ONLY  string = "host"   // Only the current host may change the variable.
+	VAR_OWNER_ONLY string = "owner"  // Only the player that created the variable may change it.
+)
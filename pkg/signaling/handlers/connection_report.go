@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Connection_Report(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into a report
+	var args structs.ConnectionReport
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	if !session.ValidConnectionStatus(args.Status) {
+		message.Send(c, structs.Packet{Opcode: "CONNECTION_ACK", Payload: "value error: unknown status"})
+		return
+	}
+
+	// Both peers must be in the same lobby
+	lobby, peer := session.SharedLobby(state, c, args.Peer)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "CONNECTION_ACK", Payload: "no peer found"})
+		return
+	}
+
+	// Failed connections fall back to the lobby relay (replies with RELAY to both peers)
+	if err := session.ReportConnection(state, lobby, c, peer, args.Status); err != nil {
+		if refusal := (*structs.RelayRefusal)(nil); errors.As(err, &refusal) {
+			message.Send(c, structs.Packet{Opcode: "RELAY_REFUSED", Payload: refusal})
+		}
+		message.Send(c, structs.Packet{Opcode: "CONNECTION_ACK", Payload: "relay unavailable"})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "CONNECTION_ACK", Payload: "ok"})
+}
@@ -3,9 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 
+	"github.com/cloudlink-omega/signaling/pkg/constants"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
 	//"github.com/cloudlink-omega/signaling/pkg/signaling/relay"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
@@ -40,20 +42,68 @@ func Create_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		return
 	}
 
+	// Check if the lobby size is valid (the host takes the first player slot)
+	if !session.ValidMaxPlayers(args.MaxPlayers) || !session.ValidMaxSpectators(args.MaxSpectators) {
+		message.Send(c, structs.Packet{Opcode: "CREATE_ACK", Payload: "invalid size"})
+		return
+	}
+
+	// Check if the migration policy is valid
+	if !session.ValidMigrationPolicy(args.MigrationPolicy) {
+		message.Send(c, structs.Packet{Opcode: "CREATE_ACK", Payload: "invalid migration policy"})
+		return
+	}
+
+	// Check if the visibility is valid
+	if !session.ValidVisibility(args.Visibility) {
+		message.Send(c, structs.Packet{Opcode: "CREATE_ACK", Payload: "invalid visibility"})
+		return
+	}
+
+	// Check if the teams are valid
+	teams, reason := session.NewTeams(args.Teams)
+	if reason != "" {
+		message.Send(c, structs.Packet{Opcode: "CREATE_ACK", Payload: "invalid teams: " + reason})
+		return
+	}
+
 	// Create the lobby
 	state.Lobbies[c.GameID][args.Name] = &structs.Lobby{
-		Name:       args.Name,
-		Lock:       &sync.RWMutex{},
-		Password:   args.Password,
-		MaxPlayers: args.MaxPlayers,
-		Locked:     args.Locked,
+		Name:          args.Name,
+		Lock:          &sync.RWMutex{},
+		Password:      args.Password,
+		MaxPlayers:    args.MaxPlayers,
+		MaxSpectators: args.MaxSpectators,
+		Locked:        args.Locked,
 		// RelayEnabled: args.EnableRelay,
-		Clients: make([]*structs.Client, 0),
+		Clients:         make([]*structs.Client, 0),
+		Muted:           make(map[string]bool),
+		Teams:           teams,
+		MigrationPolicy: args.MigrationPolicy,
+		CreatedAt:       time.Now(),
+		LastActivity:    time.Now(),
+		Visibility:      args.Visibility,
+		Invites:         make(map[string]bool),
+		RequireApproval: args.RequireApproval,
+		JoinRequests:    make(map[string]*structs.JoinRequest),
+		WaitlistEnabled: args.Waitlist,
+		Phase:           constants.PHASE_WAITING,
+		Backfill:        args.Backfill,
+		Reservations:    make(map[string]time.Time),
+		Connections:     make(map[[2]string]string),
+		Variables:       make(map[string]*structs.Variable),
+		SeedStrikes:     make(map[string]int),
+		ResultVotes:     make(map[string][]byte),
+		GameID:          c.GameID,
 	}
 	log.Infof("Lobby %s was created and %s will become the first host", args.Name, c.InstanceID)
 
-	// Set the client as the host
-	session.UpdateState(state, state.Lobbies[c.GameID][args.Name], c, 1)
+	// Set the client as the host (this also announces the lobby to subscribed peers, unless it is hidden)
+	if c.Dedicated {
+		session.HostDedicated(state, state.Lobbies[c.GameID][args.Name], c)
+	} else {
+		session.UpdateState(state, state.Lobbies[c.GameID][args.Name], c, 1)
+	}
 	message.Send(c, structs.Packet{Opcode: "CREATE_ACK", Payload: "ok"})
 
 	// Just tell the client that they are the host
@@ -64,8 +114,10 @@ func Create_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		Username:   c.Name,
 	}})
 
-	// Tell other peers about the new lobby
-	message.Broadcast(state.UninitializedPeers[c.GameID], structs.Packet{Opcode: "NEW_LOBBY", Payload: args.Name})
+	// Put the host on a team (dedicated hosts don't play)
+	if !c.Dedicated {
+		session.AutoAssign(state, state.Lobbies[c.GameID][args.Name], c)
+	}
 
 	// Create a relay
 	if args.EnableRelay {
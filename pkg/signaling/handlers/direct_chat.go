@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Direct_Chat(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Must be in a lobby to chat
+	lobby := state.Lobbies[c.GameID][c.Lobby]
+	if lobby == nil || (c.State != 1 && c.State != 2 && c.State != 3) {
+		message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.ChatArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	// The recipient must be in the same lobby
+	recipient := session.Get(session.Without(session.Audience(lobby), c), args.Recipient)
+	if recipient == nil {
+		message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: "no peer found"})
+		return
+	}
+
+	if reason := checkChat(state, lobby, c, args.Message); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: reason})
+		return
+	}
+
+	message.Send(recipient, structs.Packet{Opcode: "DIRECT_CHAT", Payload: structs.ChatMessage{
+		Sender: structs.NewPeer{
+			UserID:     c.UserID,
+			InstanceID: c.InstanceID,
+			PublicKey:  c.PublicKey,
+			Username:   c.Name,
+		},
+		Message: args.Message,
+	}})
+	message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: "ok"})
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"slices"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
 	"github.com/cloudlink-omega/signaling/pkg/structs"
 )
 
@@ -11,24 +15,28 @@ func Find_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet)
 		return
 	}
 
-	// Check if the lobby exists
-	lobby := state.Lobbies[c.GameID][wsMsg.Payload.(string)]
-	if lobby == nil {
+	// Either the lobby name, or a query with the name and the phases to look for
+	var query structs.FindQuery
+	if name, ok := wsMsg.Payload.(string); ok {
+		query.Name = name
+	} else if err := decodeArgs(wsMsg.Payload, &query); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	// Check if the lobby exists (invite-only lobbies are hidden from everyone that isn't in them)
+	lobby := state.Lobbies[c.GameID][query.Name]
+	if lobby == nil || (lobby.Visibility == constants.VISIBILITY_INVITE_ONLY && c.Lobby != lobby.Name) {
+		message.Send(c, structs.Packet{Opcode: "FIND_ACK", Payload: "not found"})
+		return
+	}
+
+	// Lobbies in other phases aren't what the client is looking for
+	if len(query.Phases) > 0 && !slices.Contains(query.Phases, lobby.Phase) {
 		message.Send(c, structs.Packet{Opcode: "FIND_ACK", Payload: "not found"})
 		return
 	}
 
 	// Return info about the lobby
-	message.Send(c, structs.Packet{Opcode: "FIND_ACK", Payload: &structs.FindLobbyArgs{
-		Host: structs.NewPeer{
-			UserID:     lobby.Host.UserID,
-			InstanceID: lobby.Host.InstanceID,
-			PublicKey:  lobby.Host.PublicKey,
-			Username:   lobby.Host.Name,
-		},
-		MaxPlayers:       lobby.MaxPlayers,
-		CurrentPlayers:   uint64(len(lobby.Clients)),
-		CurrentlyLocked:  lobby.Locked,
-		PasswordRequired: lobby.Password != "",
-	}})
+	message.Send(c, structs.Packet{Opcode: "FIND_ACK", Payload: session.Describe(lobby)})
 }
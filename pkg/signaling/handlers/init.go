@@ -30,7 +30,23 @@ func Init(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
 		return
 	}
 
-	if state.BypassDB {
+	// Dedicated hosts authenticate with the game's API key instead of an account
+	if args.APIKey != "" {
+		if !session.ValidHostKey(state, c.GameID, args.APIKey) {
+			session.CloseWithViolationMessage(c, "unauthorized")
+			return
+		}
+		c.Dedicated = true
+		c.Name = args.Username
+		if c.Name == "" {
+			c.Name = "Dedicated host"
+		}
+
+		// Derive a UserID based on the current instance ID but ONLY the first part, not the UGI
+		c.UserID = "HOST_" + c.InstanceID[:strings.Index(c.InstanceID, "_")]
+	}
+
+	if state.BypassDB && !c.Dedicated {
 
 		// Try to derive username from token
 		if !c.TokenWasPresent {
@@ -52,7 +68,9 @@ func Init(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
 	}
 
 	if !state.BypassDB {
-		if !c.AuthedWithCookie {
+
+		// Dedicated hosts have no account, but are still subject to the game checks below
+		if !c.AuthedWithCookie && !c.Dedicated {
 			if !c.TokenWasPresent {
 				c.Token = args.Token
 			}
@@ -73,7 +91,7 @@ func Init(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
 			}
 		}
 
-		// Check if they are a developer of the game
+		// Check if they are a developer of the game (dedicated hosts never are)
 		var found bool
 		for _, dev := range c.Game.Developer.DeveloperMembers {
 			if dev.UserID == c.UserID {
@@ -129,5 +147,6 @@ func Init(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
 		InstanceID: c.InstanceID,
 		UserID:     c.UserID,
 		Username:   c.Name,
+		Dedicated:  c.Dedicated,
 	}})
 }
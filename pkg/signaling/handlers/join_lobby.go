@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 
+	"github.com/cloudlink-omega/signaling/pkg/constants"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
 	"github.com/cloudlink-omega/signaling/pkg/structs"
@@ -26,64 +27,85 @@ func Join_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet)
 		return
 	}
 
-	// Check if the lobby exists
-	lobby := state.Lobbies[c.GameID][args.Name]
-	if lobby == nil {
-		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "not found"})
+	// Dedicated hosts own lobbies rather than joining them
+	if c.Dedicated {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "dedicated host"})
 		return
 	}
 
-	// Check if the lobby is locked
-	if lobby.Locked {
-		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "locked"})
+	// Clients in a lobby have to leave it before joining another one
+	if c.State != 0 {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "already in a lobby"})
 		return
 	}
 
-	// Check if the lobby is full (ignore if lobby.MaxPlayers == -1)
-	if lobby.MaxPlayers != -1 && int64(len(lobby.Clients)) >= lobby.MaxPlayers {
-		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "full"})
-		return
+	// Find the lobby by invite code, or by name
+	var lobby *structs.Lobby
+	invited := args.Invite != ""
+	if invited {
+		lobby = session.FindInvite(state, c.GameID, args.Invite)
+		if lobby == nil {
+			message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "invalid invite"})
+			return
+		}
+	} else {
+		lobby = state.Lobbies[c.GameID][args.Name]
+		if lobby == nil {
+			message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "not found"})
+			return
+		}
+
+		// Invite-only lobbies can't be joined by name
+		if lobby.Visibility == constants.VISIBILITY_INVITE_ONLY {
+			message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "invite required"})
+			return
+		}
 	}
 
-	// Check if the password is correct
-	if lobby.Password != "" && lobby.Password != args.Password {
-		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "password"})
+	// Check if the lobby is locked (invites bypass the lock)
+	if lobby.Locked && !invited {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "locked"})
 		return
 	}
 
-	// Set the client as a member
-	session.UpdateState(state, lobby, c, 2)
-	message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "ok"})
+	// A party leader brings the whole party along
+	group, reason := session.Group(state, c)
+	if reason != "" {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: reason})
+		return
+	}
 
-	if lobby.Host != nil {
+	// Players can only join while the lobby is waiting for a match, or during one if backfill is allowed.
+	// Check if there is room for everyone in the lobby (full lobbies may have a waitlist, but only for single clients)
+	vacancy := session.Joinable(lobby, args.Spectate)
+	if vacancy == "" {
+		vacancy = session.Vacancy(lobby, args.Spectate, group)
+	}
+	if vacancy != "" && !(vacancy != "spectating disabled" && lobby.WaitlistEnabled && len(group) == 1) {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: vacancy})
+		return
+	}
 
-		// Tell the peer about the current host
-		message.Send(c, structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
-			UserID:     lobby.Host.UserID,
-			InstanceID: lobby.Host.InstanceID,
-			PublicKey:  lobby.Host.PublicKey,
-			Username:   lobby.Host.Name,
-		}})
+	// Check if the password is correct (invites bypass the password)
+	if lobby.Password != "" && lobby.Password != args.Password && !invited {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "password"})
+		return
+	}
 
-		// Tell the host and other peers about the new client
-		message.Send(lobby.Host, structs.Packet{Opcode: "NEW_PEER", Payload: structs.NewPeer{
-			UserID:     c.UserID,
-			InstanceID: c.InstanceID,
-			PublicKey:  c.PublicKey,
-			Username:   c.Name,
-		}})
+	// Wait for room to open up, or for the match to end
+	if vacancy != "" {
+		session.Enqueue(state, lobby, c, args.Spectate, invited)
+		return
 	}
 
-	// Tell existing members about the new peer
-	message.Broadcast(session.Without(lobby.Clients, c), structs.Packet{Opcode: "PEER_JOIN", Payload: structs.NewPeer{
-		UserID:     c.UserID,
-		InstanceID: c.InstanceID,
-		PublicKey:  c.PublicKey,
-		Username:   c.Name,
-	}})
+	// Ask the host first if the lobby is curated (invites are already approved)
+	if lobby.RequireApproval && !invited {
+		session.RequestJoin(state, lobby, c, args.Spectate, group[1:])
+		return
+	}
 
-	// Tell the peer about the relay (if present)
-	if lobby.RelayEnabled {
-		message.Send(c, structs.Packet{Opcode: "RELAY", Payload: lobby.RelayKey})
+	// Set the client (and its party) as members, or as spectators
+	if reason := session.Join(state, lobby, group, args.Spectate); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: reason})
 	}
 }
@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"crypto/rand"
+	"time"
 
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
 	"github.com/cloudlink-omega/signaling/pkg/structs"
 )
 
-func Keepalive(state *structs.Server, c *structs.Client) {
+func Keepalive(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+
+	// Clients may report their latency (in milliseconds) with each keepalive
+	if latency, ok := wsMsg.Payload.(float64); ok && latency >= 0 {
+		c.ReportedLatency.Store(int64(latency * float64(time.Millisecond)))
+	}
+
 	random_value := make([]byte, 16)
 	rand.Read(random_value)
 	message.Send(c, structs.Packet{Opcode: "KEEPALIVE_ACK", Payload: random_value})
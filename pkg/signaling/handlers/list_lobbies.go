@@ -1,20 +1,41 @@
 package handlers
 
 import (
+	"encoding/json"
+
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
 	"github.com/cloudlink-omega/signaling/pkg/structs"
 )
 
-func List_Lobbies(state *structs.Server, c *structs.Client) {
+func List_Lobbies(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
 	if !c.Valid {
 		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
 		return
 	}
 
+	// A query (even an empty one) gets a description of each matching lobby, including its phase
+	if wsMsg.Payload != nil {
+		query := &structs.LobbyQuery{}
+		raw, err := json.Marshal(wsMsg.Payload)
+		if err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+		if err := json.Unmarshal(raw, query); err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "LIST_ACK", Payload: session.List(state, c.GameID, query)})
+		return
+	}
+
 	// Return list of lobbies
 	var lobbies []string
-	for name := range state.Lobbies[c.GameID] {
-		lobbies = append(lobbies, name)
+	for name, lobby := range state.Lobbies[c.GameID] {
+		if session.IsListed(lobby) {
+			lobbies = append(lobbies, name)
+		}
 	}
 
 	if len(lobbies) == 0 {
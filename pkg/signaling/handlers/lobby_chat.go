@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Lobby_Chat(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Must be in a lobby to chat
+	lobby := state.Lobbies[c.GameID][c.Lobby]
+	if lobby == nil || (c.State != 1 && c.State != 2 && c.State != 3) {
+		message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.ChatArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	if reason := checkChat(state, lobby, c, args.Message); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: reason})
+		return
+	}
+
+	// Deliver the message to everyone else in the lobby
+	message.Broadcast(session.Without(session.Audience(lobby), c), structs.Packet{Opcode: "LOBBY_CHAT", Payload: structs.ChatMessage{
+		Sender: structs.NewPeer{
+			UserID:     c.UserID,
+			InstanceID: c.InstanceID,
+			PublicKey:  c.PublicKey,
+			Username:   c.Name,
+		},
+		Message: args.Message,
+	}})
+	message.Send(c, structs.Packet{Opcode: "CHAT_ACK", Payload: "ok"})
+}
+
+// checkChat verifies that the client is allowed to send the given chat message.
+//
+// It returns an empty string if the message may be delivered, or the reason
+// it was refused otherwise. Accepted messages count toward the rate limit of the
+// client's user, which is shared by all of the user's connections.
+func checkChat(state *structs.Server, lobby *structs.Lobby, c *structs.Client, text string) string {
+	if lobby.Muted[c.UserID] {
+		return "muted"
+	}
+
+	if text == "" {
+		return "empty message"
+	}
+
+	if len(text) > constants.CHAT_MAX_LENGTH {
+		return "message too long"
+	}
+
+	if !session.AllowChat(state, c) {
+		return "rate limited"
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"slices"
 
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
@@ -16,15 +17,6 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		return
 	}
 
-	// Must be the lobby host to manage the lobby
-	if c.State != 1 {
-		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
-		return
-	}
-
-	// Get lobby
-	lobby := state.Lobbies[c.GameID][c.Lobby]
-
 	// Try to parse the Payload into args
 	var args structs.ManageLobbyArgs
 	raw, err := json.Marshal(wsMsg.Payload)
@@ -37,15 +29,54 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		return
 	}
 
+	// Must be the lobby host to manage the lobby
+	lobby := session.HostedLobby(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
 	// Handle lobby
 	switch args.Method {
 	case "lock":
 		lobby.Locked = true
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
 
 	case "unlock":
 		lobby.Locked = false
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
+
+	case "set_phase":
+		phase, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (phase) should be a string"})
+			return
+		}
+
+		if reason := session.SetPhase(state, lobby, phase); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+		// Let waiting clients in once the lobby is waiting for the next match
+		session.AdmitWaitlist(state, lobby)
+
+	case "allow_backfill":
+		allowed, ok := args.Args.(bool)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (allow backfill) should be a boolean"})
+			return
+		}
+
+		lobby.Backfill = allowed
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
+
+		// Let waiting clients into the match in progress
+		session.AdmitWaitlist(state, lobby)
 
 	case "kick":
 		id, ok := args.Args.(string)
@@ -55,7 +86,7 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		}
 
 		// Get the client to kick
-		client := session.Get(lobby.Clients, id)
+		client := session.Get(slices.Concat(lobby.Clients, lobby.Spectators), id)
 		if client == nil {
 			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no peer found"})
 			return
@@ -65,6 +96,27 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		session.CloseWithWarningMessage(client, "You have been kicked from the lobby.")
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
 
+	case "mute", "unmute":
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (peer id) should be a string"})
+			return
+		}
+
+		// Get the client to (un)mute
+		client := session.Get(slices.Concat(lobby.Clients, lobby.Spectators), id)
+		if client == nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no peer found"})
+			return
+		}
+
+		if args.Method == "mute" {
+			lobby.Muted[client.UserID] = true
+		} else {
+			delete(lobby.Muted, client.UserID)
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
 	case "change_password":
 		newPassword, ok := args.Args.(string)
 		if !ok {
@@ -74,44 +126,235 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 
 		lobby.Password = newPassword
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
 
 	case "change_max_players":
-		maxPlayers, ok := args.Args.(int64)
-		if !ok {
+		maxPlayers, ok := args.Args.(float64)
+		if !ok || maxPlayers != float64(int64(maxPlayers)) {
 			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (max players) should be an integer"})
 			return
 		}
 
-		if maxPlayers < -1 {
-			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: argument (max players) should at least be -1 (unlimited), greater than or equal to than the current number of peers in the lobby"})
+		// Don't update the size to be smaller than the current size (ignore if setting to unlimited)
+		if reason := session.Resizable(lobby, int64(maxPlayers)); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
 			return
 		}
 
-		// Don't update the size to be smaller than the current size (ignore if setting to unlimited)
-		if maxPlayers != -1 && len(lobby.Clients) > int(maxPlayers) {
-			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: new size is smaller than the current number of peers in the lobby"})
+		lobby.MaxPlayers = int64(maxPlayers)
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
+
+		// Let waiting clients into any newly opened slots
+		session.AdmitWaitlist(state, lobby)
+
+	case "change_max_spectators":
+		maxSpectators, ok := args.Args.(float64)
+		if !ok || maxSpectators != float64(int64(maxSpectators)) {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (max spectators) should be an integer"})
+			return
 		}
 
-		lobby.MaxPlayers = int64(maxPlayers)
+		if !session.ValidMaxSpectators(int64(maxSpectators)) {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: argument (max spectators) should at least be -1 (unlimited) or 0 (disabled)"})
+			return
+		}
+
+		// Spectators that are already watching may stay, but no new ones may join until there is room
+		lobby.MaxSpectators = int64(maxSpectators)
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
 
-	case "close_lobby":
+		// Let waiting spectators into any newly opened slots
+		session.AdmitWaitlist(state, lobby)
+
+	case "set_teams":
+		var teams []structs.TeamArgs
+		if err := decodeArgs(args.Args, &teams); err != nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (teams) should be a list of teams"})
+			return
+		}
+
+		if reason := session.SetTeams(state, lobby, teams); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: " + reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "assign_slot":
+		var assignment structs.AssignSlotArgs
+		if err := decodeArgs(args.Args, &assignment); err != nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (assignment) should be an object"})
+			return
+		}
+
+		// Only players can be put on a team, which leaves out spectators and dedicated hosts
+		client := session.Get(session.Roster(lobby), assignment.Peer)
+		if client == nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no peer found"})
+			return
+		}
+
+		if reason := session.AssignSlot(state, lobby, client, assignment.Team, assignment.Slot); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "change_migration_policy":
+		policy, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (migration policy) should be a string"})
+			return
+		}
+
+		if !session.ValidMigrationPolicy(policy) {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: unknown migration policy"})
+			return
+		}
+
+		lobby.MigrationPolicy = policy
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "set_successors":
+		var successors []string
+		if err := decodeArgs(args.Args, &successors); err != nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (successors) should be a list of peer ids"})
+			return
+		}
+
+		lobby.Successors = successors
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
 
-		// Uninitialize all peers in the lobby
-		for _, client := range lobby.Clients {
-			session.UpdateState(state, lobby, client, 0)
+	case "change_visibility":
+		visibility, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (visibility) should be a string"})
+			return
+		}
+
+		if !session.ValidVisibility(visibility) {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "value error: unknown visibility"})
+			return
+		}
+
+		lobby.Visibility = visibility
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
+
+	case "create_invite":
+		code, reason := session.CreateInvite(state, lobby)
+		if reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		message.Send(c, structs.Packet{Opcode: "INVITE_CREATED", Payload: code})
+
+	case "revoke_invite":
+		code, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (invite code) should be a string"})
+			return
 		}
 
-		// Update the host's state to be uninitialized
-		session.UpdateState(state, lobby, c, 0)
+		if !lobby.Invites[code] {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no invite found"})
+			return
+		}
+
+		delete(lobby.Invites, code)
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "require_approval":
+		required, ok := args.Args.(bool)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (require approval) should be a boolean"})
+			return
+		}
+
+		lobby.RequireApproval = required
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "enable_waitlist":
+		enabled, ok := args.Args.(bool)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (enable waitlist) should be a boolean"})
+			return
+		}
+
+		lobby.WaitlistEnabled = enabled
+		if !enabled {
+			session.ClearWaitlist(state, lobby)
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "reserve":
+		var reservation structs.ReserveArgs
+		if err := decodeArgs(args.Args, &reservation); err != nil {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (reservation) should be an object"})
+			return
+		}
+
+		if reason := session.Reserve(state, lobby, reservation); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "unreserve":
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (user id) should be a string"})
+			return
+		}
+
+		if !session.Unreserve(state, lobby, id) {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no reservation found"})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+		// Let waiting clients into the released slot
+		session.AdmitWaitlist(state, lobby)
+
+	case "approve", "deny":
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (peer id) should be a string"})
+			return
+		}
+
+		if reason := session.ResolveJoin(state, lobby, id, args.Method == "approve"); reason != "" {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: reason})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+
+	case "close_lobby":
+
+		// Uninitialize all peers and spectators in the lobby, then the host
+		session.CloseLobby(state, lobby)
 
 		// Tell the host that the lobby has been closed
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
 
 	case "transfer_ownership":
 
+		// Dedicated hosts are never replaced
+		if lobby.Dedicated {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "dedicated lobbies can't be transferred"})
+			return
+		}
+
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "type error: argument (peer id) should be a string"})
+			return
+		}
+
 		// Get the client to transfer ownership to
-		newHost := session.Get(lobby.Clients, args.Args.(string))
+		newHost := session.Get(lobby.Clients, id)
 		if newHost == nil {
 			message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "no peer found"})
 			return
@@ -120,11 +363,11 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 		// Acquire locks to update the lobby state
 		state.Lock.Lock()
 		c.Lock.Lock()
-		defer func(c *structs.Client) {
-			c.Lock.Unlock()
-			state.Lock.Unlock()
-		}(c)
 		func(c *structs.Client, lobby *structs.Lobby) {
+			defer func(c *structs.Client) {
+				c.Lock.Unlock()
+				state.Lock.Unlock()
+			}(c)
 
 			// Update the host's state to be a peer
 			message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "peer"})
@@ -138,7 +381,7 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 			lobby.Host = newHost
 			lobby.Clients = session.Without(lobby.Clients, newHost)
 			message.Send(newHost, structs.Packet{Opcode: "TRANSITION", Payload: "host"})
-			message.Broadcast(session.And(lobby.Clients, newHost), structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
+			message.Broadcast(session.Audience(lobby), structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
 				UserID:     newHost.UserID,
 				InstanceID: newHost.InstanceID,
 				PublicKey:  newHost.PublicKey,
@@ -148,5 +391,15 @@ func Manage_Lobby(state *structs.Server, c *structs.Client, wsMsg structs.Packet
 
 		// Tell the old host that the lobby has been transferred
 		message.Send(c, structs.Packet{Opcode: "MANAGE_ACK", Payload: "ok"})
+		session.PublishLobby(state, lobby)
+	}
+}
+
+// decodeArgs converts loosely typed method arguments into the given struct.
+func decodeArgs(in any, out any) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
 	}
+	return json.Unmarshal(raw, out)
 }
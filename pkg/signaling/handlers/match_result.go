@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Match_Result(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.MatchResultArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	lobby := session.LobbyOf(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "MATCH_RESULT_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	// Broadcasts MATCH_FINISHED once the result is accepted
+	result, reason := session.SubmitResult(state, lobby, c, args.Players)
+	if reason != "" {
+		message.Send(c, structs.Packet{Opcode: "MATCH_RESULT_ACK", Payload: reason})
+		return
+	}
+	if result == nil {
+		message.Send(c, structs.Packet{Opcode: "MATCH_RESULT_ACK", Payload: "pending"})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "MATCH_RESULT_ACK", Payload: "ok"})
+
+	session.RecordMatch(state, result)
+}
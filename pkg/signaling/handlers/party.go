@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Party(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.PartyArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	var reason string
+	switch args.Method {
+	case "create":
+		reason = session.CreateParty(state, c)
+
+	case "leave":
+		reason = session.LeaveParty(state, c)
+
+	case "invite", "kick":
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "PARTY_ACK", Payload: "type error: argument (peer id) should be a string"})
+			return
+		}
+		if args.Method == "invite" {
+			reason = session.InviteToParty(state, c, id)
+		} else {
+			reason = session.KickFromParty(state, c, id)
+		}
+
+	case "accept", "decline":
+		id, ok := args.Args.(string)
+		if !ok {
+			message.Send(c, structs.Packet{Opcode: "PARTY_ACK", Payload: "type error: argument (party id) should be a string"})
+			return
+		}
+		if args.Method == "accept" {
+			reason = session.AcceptParty(state, c, id)
+		} else {
+			reason = session.DeclineParty(state, c, id)
+		}
+
+	default:
+		message.Send(c, structs.Packet{Opcode: "PARTY_ACK", Payload: "unknown method"})
+		return
+	}
+
+	if reason != "" {
+		message.Send(c, structs.Packet{Opcode: "PARTY_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "PARTY_ACK", Payload: "ok"})
+}
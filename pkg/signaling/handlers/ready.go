@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Ready(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Only the host and members take part in the ready check
+	lobby := state.Lobbies[c.GameID][c.Lobby]
+	if lobby == nil || (c.State != 1 && c.State != 2) {
+		message.Send(c, structs.Packet{Opcode: "READY_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	// Use the given value if there is one, otherwise toggle
+	if ready, ok := wsMsg.Payload.(bool); ok {
+		c.Ready = ready
+	} else {
+		c.Ready = !c.Ready
+	}
+	message.Send(c, structs.Packet{Opcode: "READY_ACK", Payload: "ok"})
+
+	// Tell everyone in the lobby about the new ready state
+	message.Broadcast(session.Audience(lobby), structs.Packet{Opcode: "READY_STATE", Payload: structs.ReadyState{
+		InstanceID: c.InstanceID,
+		Ready:      c.Ready,
+	}})
+}
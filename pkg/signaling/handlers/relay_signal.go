@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/relay"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+	"github.com/pion/webrtc/v3"
+)
+
+func Relay_Offer(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, r := parseRelaySignal(state, c, wsMsg)
+	if r == nil {
+		return
+	}
+
+	if args.SDP == nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "value error: missing sdp"})
+		return
+	}
+
+	// Refuse tickets early, they are checked again once the connection opens
+	if !relay.VerifyTicket(state.RelaySecret, c, r, args.Ticket) {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "invalid ticket"})
+		return
+	}
+
+	// Replies with RELAY_ANSWER, followed by RELAY_ICE for each of the relay's candidates
+	answer, err := relay.Offer(state, r, c, *args.SDP, args.Ticket, func(candidate webrtc.ICECandidateInit) {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ICE", Payload: structs.RelaySignal{Relay: r.Id, Candidate: &candidate}})
+	})
+	if refusal := (*structs.RelayRefusal)(nil); errors.As(err, &refusal) {
+		message.Send(c, structs.Packet{Opcode: "RELAY_REFUSED", Payload: refusal})
+		return
+	}
+	if err != nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: err.Error()})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "RELAY_ANSWER", Payload: structs.RelaySignal{Relay: r.Id, SDP: answer}})
+}
+
+func Relay_Ice(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, r := parseRelaySignal(state, c, wsMsg)
+	if r == nil {
+		return
+	}
+
+	if args.Candidate == nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "value error: missing candidate"})
+		return
+	}
+
+	if err := relay.AddCandidate(r, c, *args.Candidate); err != nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: err.Error()})
+	}
+}
+
+// Relay_Ticket issues a fresh relay ticket, for peers whose ticket expired before they
+// (re)connected to the relay. Dedicated hosts name the lobby in the payload.
+func Relay_Ticket(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	name, _ := wsMsg.Payload.(string)
+	lobby := session.LobbyOf(state, c, name)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	ticket, ok := session.RenewTicket(state, lobby, c)
+	if !ok {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "no relay found"})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "RELAY_TICKET", Payload: ticket})
+}
+
+// parseRelaySignal reads a relay signalling message and finds the relay it is meant for.
+// Returns a nil relay if the message was rejected.
+func parseRelaySignal(state *structs.Server, c *structs.Client, wsMsg structs.Packet) (structs.RelaySignal, *structs.Relay) {
+	var args structs.RelaySignal
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return args, nil
+	}
+
+	// Try to parse the Payload into args
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return args, nil
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return args, nil
+	}
+
+	// Only peers in the relay's lobby may connect to it
+	r := session.FindRelay(state, c, args.Relay)
+	if r == nil {
+		message.Send(c, structs.Packet{Opcode: "RELAY_ACK", Payload: "no relay found"})
+		return args, nil
+	}
+	return args, r
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Seed_Start(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, ok := parseSeedArgs(c, wsMsg)
+	if !ok {
+		return
+	}
+
+	// Must be the lobby host to start a round
+	lobby := session.HostedLobby(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Broadcasts SEED_COMMIT_REQUEST
+	if reason := session.StartSeed(state, lobby); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: "ok"})
+}
+
+func Seed_Commit(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, ok := parseSeedArgs(c, wsMsg)
+	if !ok {
+		return
+	}
+
+	lobby := session.LobbyOf(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	if reason := session.CommitSeed(state, lobby, c, args.Value); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: "ok"})
+}
+
+func Seed_Reveal(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, ok := parseSeedArgs(c, wsMsg)
+	if !ok {
+		return
+	}
+
+	lobby := session.LobbyOf(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	if reason := session.RevealSeed(state, lobby, c, args.Value); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "SEED_ACK", Payload: "ok"})
+}
+
+// parseSeedArgs reads the arguments of a seed message. Returns false if the message was rejected.
+func parseSeedArgs(c *structs.Client, wsMsg structs.Packet) (structs.SeedArgs, bool) {
+	var args structs.SeedArgs
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return args, false
+	}
+
+	// Try to parse the Payload into args (SEED_START may be sent without one)
+	if wsMsg.Payload != nil {
+		raw, err := json.Marshal(wsMsg.Payload)
+		if err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return args, false
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return args, false
+		}
+	}
+	return args, true
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Start(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.StartArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	// Must be the lobby host to start the match
+	lobby := session.HostedLobby(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	if args.Cancel {
+		if !session.CancelCountdown(state, lobby) {
+			message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: "not starting"})
+			return
+		}
+		message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: "ok"})
+
+		// Let waiting clients in now that the lobby is waiting again
+		session.AdmitWaitlist(state, lobby)
+		return
+	}
+
+	length := time.Duration(args.Countdown) * time.Second
+	if length == 0 {
+		length = constants.COUNTDOWN_DEFAULT
+	}
+	if length < 0 || length > constants.COUNTDOWN_MAX {
+		message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: "value error: countdown is out of range"})
+		return
+	}
+
+	// Everyone must be ready unless the host forces the start
+	if !args.Force {
+		for _, client := range session.Roster(lobby) {
+			if !client.Ready {
+				message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: "not ready"})
+				return
+			}
+		}
+	}
+
+	if reason := session.StartCountdown(state, lobby, length); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "START_ACK", Payload: "ok"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Subscribe_Lobbies(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into a query (an empty query matches every listed lobby)
+	query := &structs.LobbyQuery{}
+	if wsMsg.Payload != nil {
+		raw, err := json.Marshal(wsMsg.Payload)
+		if err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+		if err := json.Unmarshal(raw, query); err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+	}
+
+	// Replies with LOBBY_SNAPSHOT
+	session.Subscribe(state, c, query)
+}
+
+func Unsubscribe_Lobbies(state *structs.Server, c *structs.Client) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	session.Unsubscribe(state, c)
+	message.Send(c, structs.Packet{Opcode: "UNSUBSCRIBE_ACK", Payload: "ok"})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Switch_Team(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Only the host and members can be on a team
+	lobby := state.Lobbies[c.GameID][c.Lobby]
+	if lobby == nil || (c.State != 1 && c.State != 2) {
+		message.Send(c, structs.Packet{Opcode: "TEAM_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	// Try to parse the Payload into args
+	var args structs.SwitchTeamArgs
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return
+	}
+
+	if reason := session.AssignSlot(state, lobby, c, args.Team, args.Slot); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "TEAM_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "TEAM_ACK", Payload: "ok"})
+}
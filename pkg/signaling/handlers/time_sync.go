@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Time_Sync answers an NTP-style clock synchronization request. The client sends its
+// own clock reading (t0) and gets back the server's receive (t1) and send (t2) times,
+// from which it can estimate both its round-trip time and its clock offset.
+func Time_Sync(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	t0, ok := wsMsg.Payload.(float64)
+	if !ok {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "type error: payload (t0) should be a number"})
+		return
+	}
+
+	reply := structs.TimeSync{
+		T0: t0,
+		T1: session.Millis(wsMsg.ReceivedAt),
+	}
+
+	now := time.Now()
+	if elapsed, ok := session.MatchClock(state, c, now); ok {
+		reply.MatchTime = float64(elapsed.Microseconds()) / 1000
+	}
+	reply.T2 = session.Millis(now)
+
+	message.Send(c, structs.Packet{Opcode: "TIME_SYNC", Payload: reply})
+}
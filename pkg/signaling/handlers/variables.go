@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+func Var_Get(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, lobby := parseVarArgs(state, c, wsMsg)
+	if lobby == nil {
+		return
+	}
+
+	variable, ok := session.GetVariable(state, lobby, args.Key)
+	if !ok {
+		message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: "not found"})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "VAR_VALUE", Payload: variable})
+}
+
+func Var_Set(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, lobby := parseVarArgs(state, c, wsMsg)
+	if lobby == nil {
+		return
+	}
+
+	// Broadcasts VAR_CHANGED
+	if reason := session.SetVariable(state, lobby, c, args); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: "ok"})
+}
+
+func Var_Delete(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	args, lobby := parseVarArgs(state, c, wsMsg)
+	if lobby == nil {
+		return
+	}
+
+	// Broadcasts VAR_CHANGED
+	if reason := session.DeleteVariable(state, lobby, c, args); reason != "" {
+		message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: reason})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: "ok"})
+}
+
+func Var_Watch(state *structs.Server, c *structs.Client, wsMsg structs.Packet) {
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return
+	}
+
+	// Try to parse the Payload into args (an empty payload watches every variable)
+	var args structs.VarWatchArgs
+	if wsMsg.Payload != nil {
+		raw, err := json.Marshal(wsMsg.Payload)
+		if err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			session.CloseWithViolationMessage(c, err.Error())
+			return
+		}
+	}
+
+	lobby := session.LobbyOf(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: "not in a lobby"})
+		return
+	}
+
+	message.Send(c, structs.Packet{Opcode: "VAR_SNAPSHOT", Payload: session.WatchVariables(state, lobby, c, args.Keys)})
+}
+
+// parseVarArgs reads the arguments of a variable operation and finds the lobby it
+// applies to. Returns a nil lobby if the message was rejected.
+func parseVarArgs(state *structs.Server, c *structs.Client, wsMsg structs.Packet) (structs.VarArgs, *structs.Lobby) {
+	var args structs.VarArgs
+	if !c.Valid {
+		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unauthorized"})
+		return args, nil
+	}
+
+	// Try to parse the Payload into args
+	raw, err := json.Marshal(wsMsg.Payload)
+	if err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return args, nil
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		session.CloseWithViolationMessage(c, err.Error())
+		return args, nil
+	}
+
+	lobby := session.LobbyOf(state, c, args.Lobby)
+	if lobby == nil {
+		message.Send(c, structs.Packet{Opcode: "VAR_ACK", Payload: "not in a lobby"})
+		return args, nil
+	}
+	return args, lobby
+}
@@ -0,0 +1,101 @@
+package latency
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Track starts measuring the round-trip time of the given client. The server sends
+// a timestamped WebSocket ping every interval, and the client's WebSocket
+// implementation answers with a pong carrying the same timestamp.
+//
+// Each pong also counts as a heartbeat and pushes the client's read deadline
+// back by timeout, so that a client which stops answering pings is disconnected
+// once the deadline passes. If a ping can't be written, the connection is closed.
+//
+// Must be called before the client's read loop starts. Tracking stops once c.Done is closed.
+func Track(c *structs.Client, interval time.Duration, timeout time.Duration) {
+	c.Conn.SetPongHandler(func(appData string) error {
+		if timeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		if len(appData) != 8 {
+			return nil
+		}
+		sent := time.Unix(0, int64(binary.BigEndian.Uint64([]byte(appData))))
+		Sample(c, time.Since(sent))
+		return nil
+	})
+
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.Done:
+				return
+			case <-ticker.C:
+				if err := Ping(c); err != nil {
+					log.Debugf("Client %s ping error, closing connection: %s", c.InstanceID, err.Error())
+					c.Conn.Close()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Ping sends a single timestamped WebSocket ping to the client.
+func Ping(c *structs.Client) error {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(time.Now().UnixNano()))
+	return c.Conn.WriteControl(websocket.PingMessage, payload, time.Now().Add(time.Second))
+}
+
+// Sample folds a new round-trip measurement into the client's smoothed RTT and jitter,
+// using the same weights as TCP's retransmission timer (RFC 6298).
+func Sample(c *structs.Client, rtt time.Duration) {
+	if rtt < 0 {
+		return
+	}
+
+	srtt := time.Duration(c.RTT.Load())
+	if srtt == 0 {
+		c.RTT.Store(int64(rtt))
+		c.Jitter.Store(int64(rtt / 2))
+		return
+	}
+
+	delta := srtt - rtt
+	if delta < 0 {
+		delta = -delta
+	}
+	jitter := time.Duration(c.Jitter.Load())
+	c.Jitter.Store(int64(jitter - jitter/4 + delta/4))
+	c.RTT.Store(int64(srtt - srtt/8 + rtt/8))
+}
+
+// Of returns the best known latency of the client: the server-measured round-trip
+// time if there is one, or the latency the client reported otherwise.
+// Returns zero if neither is known.
+func Of(c *structs.Client) time.Duration {
+	if rtt := Measured(c); rtt > 0 {
+		return rtt
+	}
+	return time.Duration(c.ReportedLatency.Load())
+}
+
+// Measured returns the round-trip time measured by the server, or zero if it hasn't
+// been measured yet. Unlike the reported latency, the client can't make it up.
+func Measured(c *structs.Client) time.Duration {
+	return time.Duration(c.RTT.Load())
+}
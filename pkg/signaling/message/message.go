@@ -1,6 +1,8 @@
 package message
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2/log"
 
 	"github.com/cloudlink-omega/signaling/pkg/structs"
@@ -24,6 +26,7 @@ func Read(c *structs.Client) (structs.Packet, error) {
 	}
 
 	var clientMsg structs.Packet
+	clientMsg.ReceivedAt = time.Now()
 	if err := json.Unmarshal(raw, &clientMsg); err != nil {
 		log.Errorf("Invalid message format")
 		return structs.Packet{}, err
@@ -3,47 +3,62 @@ package relay
 import (
 	"crypto/rand"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 
+	"github.com/cloudlink-omega/signaling/pkg/constants"
 	"github.com/cloudlink-omega/signaling/pkg/structs"
-	peer "github.com/muka/peerjs-go"
 	"github.com/oklog/ulid/v2"
 	"github.com/pion/webrtc/v3"
 )
 
+// ErrClosed is returned when a client tries to connect to a relay that has been closed.
+var ErrClosed = errors.New("relay closed")
+
+// ICEServers are offered to the relay's peer connections.
+var ICEServers = []webrtc.ICEServer{
+	{
+		URLs: []string{"stun:vpn.mikedev101.cc:3478", "stun:vpn.mikedev101.cc:5349"},
+	},
+	{
+		URLs:       []string{"turn:vpn.mikedev101.cc:5349", "turn:vpn.mikedev101.cc:3478"},
+		Username:   "free",
+		Credential: "free",
+	},
+}
+
 func SpawnRelay(c *structs.Client, state *structs.Server, lobby_name string) (*structs.Relay, error) {
-	if state.Relays[c.GameID] != nil {
-		return state.Relays[c.GameID][lobby_name], nil
-	}
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
 
-	config := peer.NewOptions()
-	config.PingInterval = 500
-	config.Debug = 2
-	config.Configuration.ICEServers = []webrtc.ICEServer{
-		{
-			URLs: []string{"stun:vpn.mikedev101.cc:3478", "stun:vpn.mikedev101.cc:5349"},
-		},
-		{
-			URLs:       []string{"turn:vpn.mikedev101.cc:5349", "turn:vpn.mikedev101.cc:3478"},
-			Username:   "free",
-			Credential: "free",
-		},
+	// Reuse the lobby's relay if it already has one
+	if existing := state.Relays[c.GameID][lobby_name]; existing != nil {
+		return existing, nil
 	}
 
-	relayid := ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
-	relayPeer, err := peer.NewPeer(relayid, config)
-	if err != nil {
-		log.Errorf("Failed to create relay peer: %s", err)
-		state.Lobbies[c.GameID][lobby_name].RelayEnabled = false
-		return nil, err
+	// Enforce the server and game relay quotas
+	maxRelays, maxPeers, byteRate := limits(state, c.GameID)
+	if state.MaxRelays > 0 && countRelays(state) >= state.MaxRelays {
+		return nil, &structs.RelayRefusal{Reason: "server relay limit", Limit: int64(state.MaxRelays)}
+	}
+	if len(state.Relays[c.GameID]) >= maxRelays {
+		return nil, &structs.RelayRefusal{Reason: "game relay limit", Limit: int64(maxRelays)}
 	}
 
+	relayid := ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
 	relayObj := &structs.Relay{
-		Handler:   relayPeer,
 		Id:        relayid,
+		GameID:    c.GameID,
+		Lobby:     lobby_name,
+		Lock:      &sync.Mutex{},
+		Peers:     make(map[string]*structs.RelayPeer),
+		MaxPeers:  maxPeers,
+		ByteRate:  byteRate,
+		Window:    time.Now(),
 		Close:     make(chan bool),
 		CloseDone: make(chan bool),
 	}
@@ -53,10 +68,8 @@ func SpawnRelay(c *structs.Client, state *structs.Server, lobby_name string) (*s
 		state.Relays[c.GameID] = make(map[string]*structs.Relay)
 	}
 
-	if state.Relays[c.GameID][lobby_name] == nil {
-		log.Infof("Game %s lobby %s relay storage has been created\n", c.GameID, lobby_name)
-		state.Relays[c.GameID][lobby_name] = relayObj
-	}
+	log.Infof("Game %s lobby %s relay storage has been created\n", c.GameID, lobby_name)
+	state.Relays[c.GameID][lobby_name] = relayObj
 
 	log.Infof("Created relay peer %s for game %s lobby %s", relayid, c.GameID, lobby_name)
 
@@ -64,69 +77,298 @@ func SpawnRelay(c *structs.Client, state *structs.Server, lobby_name string) (*s
 	return relayObj, nil
 }
 
+// HandleRelay waits for the relay to be closed and then disconnects every peer.
 func HandleRelay(_ *structs.Server, r *structs.Relay) {
-	p := r.Handler
-	defer p.Destroy()
+	<-r.Close
+	log.Infof("Relay peer %s got close signal", r.Id)
 
-	p.On("connection", func(data any) {
-		Conn := data.(*peer.DataConnection)
+	r.Lock.Lock()
+	r.Closed = true
+	peers := r.Peers
+	r.Peers = make(map[string]*structs.RelayPeer)
+	r.Lock.Unlock()
 
-		Conn.On("open", func(data any) {
-			log.Debugf("Peer %s Connected to relay %s", Conn.GetPeerID(), r.Id)
-		})
+	for _, peer := range peers {
+		peer.Conn.Close()
+	}
+	r.CloseDone <- true
+}
 
-		Conn.On("data", func(data any) {
+// Offer answers a client's offer to connect to the relay. Any previous connection
+// of the client is replaced. ICE candidates gathered by the relay are handed to
+// onCandidate, which should pass them on to the client.
+func Offer(state *structs.Server, r *structs.Relay, c *structs.Client, offer webrtc.SessionDescription, ticket string, onCandidate func(webrtc.ICECandidateInit)) (*webrtc.SessionDescription, error) {
+	config := webrtc.Configuration{ICEServers: ICEServers}
+	if state.TURNOnly {
+		config.ICETransportPolicy = webrtc.ICETransportPolicyRelay
+	}
 
-			byteStream := data.([]byte)
+	conn, err := webrtc.NewPeerConnection(config)
+	if err != nil {
+		return nil, err
+	}
+	peer := &structs.RelayPeer{Client: c, Conn: conn, Ticket: ticket}
 
-			// Trim (UTF-16) header???
-			byteStream = byteStream[3:]
+	conn.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		onCandidate(candidate.ToJSON())
+	})
 
-			log.Debugf("Received data from peer %s in relay %s: %s\n", Conn.GetPeerID(), r.Id, byteStream)
+	conn.OnDataChannel(func(channel *webrtc.DataChannel) {
+		channel.OnOpen(func() {
 
-			packet := structs.Packet{}
-			err := json.Unmarshal(fmt.Appendf(nil, "%s", byteStream), &packet)
-			if err != nil {
-				log.Error(err)
+			// Drop peers without a valid ticket, or that are no longer in the lobby
+			if !authorized(state, r, peer) {
+				log.Warnf("Peer %s was refused by relay %s", c.InstanceID, r.Id)
+				remove(r, peer)
+				conn.Close()
 				return
 			}
 
-			// TODO: handle relay messages
-			log.Debugf("Packet: ", packet)
-			switch packet.Opcode {
-			case "G_MSG":
-				break
-			case "P_MSG":
-				break
-			case "G_VAR":
-				break
-			case "P_VAR":
-				break
-			case "G_LIST":
-				break
-			case "P_LIST":
-				break
-			}
-		})
-
-		Conn.On("close", func(data any) {
-			log.Debugf("Peer %s disconnected from relay %s", Conn.GetPeerID(), r.Id)
+			r.Lock.Lock()
+			defer r.Lock.Unlock()
+			peer.Channel = channel
+			log.Debugf("Peer %s connected to relay %s", c.InstanceID, r.Id)
 		})
 
-		Conn.On("error", func(data any) {
-			log.Debugf("Peer %s error in relay %s: %v", Conn.GetPeerID(), r.Id, data)
+		channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+			handleMessage(r, peer, msg.Data)
 		})
 	})
 
-	p.On("error", func(data any) {
-		log.Debugf("Relay %s peer error: %v", r.Id, data)
+	conn.OnConnectionStateChange(func(connState webrtc.PeerConnectionState) {
+		switch connState {
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
+			remove(r, peer)
+			log.Debugf("Peer %s disconnected from relay %s", c.InstanceID, r.Id)
+		}
 	})
 
-	p.On("close", func(data any) {
-		log.Infof("Relay peer %s closed", r.Id)
-	})
+	r.Lock.Lock()
 
-	<-r.Close
-	log.Infof("Relay peer %s got close signal", r.Id)
-	r.CloseDone <- true
+	// The relay may have been closed while the connection was being set up
+	if r.Closed {
+		r.Lock.Unlock()
+		conn.Close()
+		return nil, ErrClosed
+	}
+
+	previous := r.Peers[c.InstanceID]
+	if previous == nil && len(r.Peers) >= r.MaxPeers {
+		r.Lock.Unlock()
+		conn.Close()
+		return nil, &structs.RelayRefusal{Reason: "relay full", Limit: int64(r.MaxPeers)}
+	}
+	r.Peers[c.InstanceID] = peer
+	r.Lock.Unlock()
+	if previous != nil {
+		previous.Conn.Close()
+	}
+
+	if err := conn.SetRemoteDescription(offer); err != nil {
+		Disconnect(r, c)
+		return nil, err
+	}
+	answer, err := conn.CreateAnswer(nil)
+	if err != nil {
+		Disconnect(r, c)
+		return nil, err
+	}
+	if err := conn.SetLocalDescription(answer); err != nil {
+		Disconnect(r, c)
+		return nil, err
+	}
+	return conn.LocalDescription(), nil
+}
+
+// AddCandidate adds an ICE candidate sent by the client to its relay connection.
+func AddCandidate(r *structs.Relay, c *structs.Client, candidate webrtc.ICECandidateInit) error {
+	r.Lock.Lock()
+	peer := r.Peers[c.InstanceID]
+	r.Lock.Unlock()
+
+	if peer == nil {
+		return errors.New("not connected")
+	}
+	return peer.Conn.AddICECandidate(candidate)
+}
+
+// Disconnect closes the client's connection to the relay, if it has one.
+func Disconnect(r *structs.Relay, c *structs.Client) {
+	r.Lock.Lock()
+	peer := r.Peers[c.InstanceID]
+	delete(r.Peers, c.InstanceID)
+	r.Lock.Unlock()
+
+	if peer != nil {
+		peer.Conn.Close()
+	}
+}
+
+// authorized returns true if the peer connected with a valid ticket and is
+// still in the relay's lobby.
+func authorized(state *structs.Server, r *structs.Relay, peer *structs.RelayPeer) bool {
+	if !VerifyTicket(state.RelaySecret, peer.Client, r, peer.Ticket) {
+		return false
+	}
+
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	lobby := state.Lobbies[r.GameID][r.Lobby]
+	if lobby == nil || lobby.RelayKey != r.Id {
+		return false
+	}
+	return lobby.Host == peer.Client ||
+		slices.Contains(lobby.Clients, peer.Client) ||
+		slices.Contains(lobby.Spectators, peer.Client)
+}
+
+// Stats returns the relay's peer count and bandwidth counters.
+func Stats(r *structs.Relay) structs.RelayStats {
+	r.Lock.Lock()
+	peers := len(r.Peers)
+	r.Lock.Unlock()
+
+	return structs.RelayStats{
+		Relay:    r.Id,
+		Lobby:    r.Lobby,
+		Peers:    peers,
+		BytesIn:  r.BytesIn.Load(),
+		BytesOut: r.BytesOut.Load(),
+		Dropped:  r.Dropped.Load(),
+	}
+}
+
+// limits returns the relay quotas of a game. The caller must hold the state lock.
+func limits(state *structs.Server, gameID string) (maxRelays int, maxPeers int, byteRate int64) {
+	maxRelays, maxPeers, byteRate = constants.MAX_RELAYS_PER_GAME, constants.MAX_RELAY_PEERS, constants.RELAY_BYTES_PER_SEC
+	if settings := state.GameSettings[gameID]; settings != nil {
+		if settings.MaxRelays > 0 {
+			maxRelays = settings.MaxRelays
+		}
+		if settings.MaxRelayPeers > 0 {
+			maxPeers = settings.MaxRelayPeers
+		}
+		if settings.RelayByteRate > 0 {
+			byteRate = settings.RelayByteRate
+		}
+	}
+	return
+}
+
+// countRelays returns the number of relays across all games. The caller must hold the state lock.
+func countRelays(state *structs.Server) int {
+	count := 0
+	for _, relays := range state.Relays {
+		count += len(relays)
+	}
+	return count
+}
+
+// spend takes the given number of bytes from the relay's budget for the current second.
+// Returns false if the relay has already used up its budget.
+func spend(r *structs.Relay, bytes int64) bool {
+	r.Lock.Lock()
+	defer r.Lock.Unlock()
+
+	if now := time.Now(); now.Sub(r.Window) >= time.Second {
+		r.Window = now
+		r.WindowBytes = 0
+	}
+	if r.WindowBytes+bytes > r.ByteRate {
+		return false
+	}
+	r.WindowBytes += bytes
+	return true
+}
+
+// remove forgets a peer whose connection has ended, unless it has since been replaced.
+func remove(r *structs.Relay, peer *structs.RelayPeer) {
+	r.Lock.Lock()
+	defer r.Lock.Unlock()
+	if r.Peers[peer.Client.InstanceID] == peer {
+		delete(r.Peers, peer.Client.InstanceID)
+	}
+}
+
+// accepted returns true if the relay has accepted the peer's data channel and the peer
+// hasn't since been removed or replaced. The caller must hold the relay lock.
+func accepted(r *structs.Relay, peer *structs.RelayPeer) bool {
+	return peer.Channel != nil && r.Peers[peer.Client.InstanceID] == peer
+}
+
+// handleMessage forwards a packet received from a peer. G_ opcodes are sent to every
+// other peer on the relay and P_ opcodes are sent to the recipient only.
+func handleMessage(r *structs.Relay, from *structs.RelayPeer, data []byte) {
+
+	// Messages can arrive before the peer's ticket has been checked, or after it was refused
+	r.Lock.Lock()
+	ok := accepted(r, from)
+	r.Lock.Unlock()
+	if !ok {
+		log.Debugf("Dropped a message from unaccepted peer %s in relay %s", from.Client.InstanceID, r.Id)
+		return
+	}
+	r.BytesIn.Add(int64(len(data)))
+
+	var packet struct {
+		Opcode  string               `json:"opcode"`
+		Payload structs.RelayMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &packet); err != nil {
+		log.Debugf("Invalid packet from peer %s in relay %s: %s", from.Client.InstanceID, r.Id, err)
+		return
+	}
+
+	// Peers can't impersonate each other
+	packet.Payload.Origin = from.Client.InstanceID
+	raw, err := json.Marshal(structs.Packet{Opcode: packet.Opcode, Payload: packet.Payload})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	recipients := make([]*webrtc.DataChannel, 0)
+	r.Lock.Lock()
+
+	// The peer may have been refused or replaced in the meantime
+	if !accepted(r, from) {
+		r.Lock.Unlock()
+		return
+	}
+
+	switch packet.Opcode {
+	case "G_MSG", "G_VAR", "G_LIST":
+		for id, peer := range r.Peers {
+			if id != from.Client.InstanceID && peer.Channel != nil {
+				recipients = append(recipients, peer.Channel)
+			}
+		}
+
+	case "P_MSG", "P_VAR", "P_LIST":
+		if peer := r.Peers[packet.Payload.Recipient]; peer != nil && peer.Channel != nil {
+			recipients = append(recipients, peer.Channel)
+		}
+
+	default:
+		log.Debugf("Unknown opcode %s from peer %s in relay %s", packet.Opcode, from.Client.InstanceID, r.Id)
+	}
+	r.Lock.Unlock()
+
+	// Drop the message if forwarding it would exceed the relay's byte rate
+	cost := int64(len(raw) * len(recipients))
+	if !spend(r, cost) {
+		r.Dropped.Add(1)
+		return
+	}
+	r.BytesOut.Add(cost)
+
+	for _, channel := range recipients {
+		if err := channel.SendText(string(raw)); err != nil {
+			log.Debugf("Failed to forward %s in relay %s: %s", packet.Opcode, r.Id, err)
+		}
+	}
 }
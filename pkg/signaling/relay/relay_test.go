@@ -0,0 +1,293 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+	"github.com/pion/webrtc/v3"
+)
+
+// testPeer is a client connected to the relay through its own pion peer connection.
+type testPeer struct {
+	client   *structs.Client
+	conn     *webrtc.PeerConnection
+	channel  *webrtc.DataChannel
+	opened   chan struct{}
+	received chan structs.Packet
+}
+
+// newTestLobby sets up a server with one lobby, its relay, and a host and a member.
+func newTestLobby(t *testing.T) (*structs.Server, *structs.Relay, *structs.Client, *structs.Client) {
+	t.Helper()
+
+	// Only use host candidates, there is no STUN or TURN server in tests
+	servers := ICEServers
+	ICEServers = nil
+	t.Cleanup(func() { ICEServers = servers })
+
+	host := &structs.Client{InstanceID: "host", GameID: "game"}
+	member := &structs.Client{InstanceID: "member", GameID: "game"}
+	state := &structs.Server{
+		Lock:        &sync.RWMutex{},
+		Relays:      make(map[string]map[string]*structs.Relay),
+		Lobbies:     make(map[string]map[string]*structs.Lobby),
+		RelaySecret: []byte("secret"),
+	}
+
+	r, err := SpawnRelay(host, state, "lobby")
+	if err != nil {
+		t.Fatalf("SpawnRelay() error = %v", err)
+	}
+	state.Lobbies["game"] = map[string]*structs.Lobby{"lobby": {
+		Name:         "lobby",
+		GameID:       "game",
+		Lock:         &sync.RWMutex{},
+		Host:         host,
+		Clients:      []*structs.Client{member},
+		RelayEnabled: true,
+		RelayKey:     r.Id,
+	}}
+
+	t.Cleanup(func() {
+		if !closed(r) {
+			r.Close <- true
+			<-r.CloseDone
+		}
+	})
+	return state, r, host, member
+}
+
+// connect connects the client to the relay the same way RELAY_OFFER and RELAY_ICE do.
+func connect(t *testing.T, state *structs.Server, r *structs.Relay, c *structs.Client) *testPeer {
+	t.Helper()
+
+	lobby := state.Lobbies[c.GameID]["lobby"]
+	return connectWithTicket(t, state, r, c, IssueTicket(state.RelaySecret, c, lobby, time.Minute).Ticket)
+}
+
+// connectWithTicket connects the client to the relay with the given ticket.
+func connectWithTicket(t *testing.T, state *structs.Server, r *structs.Relay, c *structs.Client, ticket string) *testPeer {
+	t.Helper()
+
+	conn, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("NewPeerConnection() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	peer := &testPeer{client: c, conn: conn, opened: make(chan struct{}), received: make(chan structs.Packet, 16)}
+	peer.channel, err = conn.CreateDataChannel("relay", nil)
+	if err != nil {
+		t.Fatalf("CreateDataChannel() error = %v", err)
+	}
+	peer.channel.OnOpen(func() { close(peer.opened) })
+	peer.channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		var packet structs.Packet
+		if err := json.Unmarshal(msg.Data, &packet); err == nil {
+			peer.received <- packet
+		}
+	})
+
+	// Send the offer with all of our candidates, so only the relay's need trickling
+	offer, err := conn.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("CreateOffer() error = %v", err)
+	}
+	gathered := webrtc.GatheringCompletePromise(conn)
+	if err := conn.SetLocalDescription(offer); err != nil {
+		t.Fatalf("SetLocalDescription() error = %v", err)
+	}
+	<-gathered
+
+	// The relay's candidates can arrive before its answer has been applied
+	candidates := make(chan webrtc.ICECandidateInit, 16)
+	answer, err := Offer(state, r, c, *conn.LocalDescription(), ticket, func(candidate webrtc.ICECandidateInit) {
+		candidates <- candidate
+	})
+	if err != nil {
+		t.Fatalf("Offer() error = %v", err)
+	}
+	if err := conn.SetRemoteDescription(*answer); err != nil {
+		t.Fatalf("SetRemoteDescription() error = %v", err)
+	}
+	go func() {
+		for candidate := range candidates {
+			conn.AddICECandidate(candidate)
+		}
+	}()
+	return peer
+}
+
+// waitConnected waits until the relay has accepted the data channels of all the given clients.
+func waitConnected(t *testing.T, r *structs.Relay, clients ...*structs.Client) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		r.Lock.Lock()
+		ready := true
+		for _, c := range clients {
+			if peer := r.Peers[c.InstanceID]; peer == nil || peer.Channel == nil {
+				ready = false
+			}
+		}
+		r.Lock.Unlock()
+
+		if ready {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("peers did not connect to the relay in time")
+}
+
+// send sends a relay packet from the peer.
+func (p *testPeer) send(t *testing.T, opcode string, msg structs.RelayMessage) {
+	t.Helper()
+
+	raw, err := json.Marshal(structs.Packet{Opcode: opcode, Payload: msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.channel.SendText(string(raw)); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+}
+
+// expect waits for the peer to receive a packet and checks its opcode, origin and payload.
+func (p *testPeer) expect(t *testing.T, opcode string, origin string, payload string) {
+	t.Helper()
+
+	select {
+	case packet := <-p.received:
+		if packet.Opcode != opcode {
+			t.Errorf("%s got opcode %q, want %q", p.client.InstanceID, packet.Opcode, opcode)
+		}
+		msg, _ := packet.Payload.(map[string]any)
+		if msg["origin"] != origin {
+			t.Errorf("%s got origin %v, want %q", p.client.InstanceID, msg["origin"], origin)
+		}
+		if msg["payload"] != payload {
+			t.Errorf("%s got payload %v, want %q", p.client.InstanceID, msg["payload"], payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not receive %s", p.client.InstanceID, opcode)
+	}
+}
+
+// expectNothing checks that the peer receives no packet for a short while.
+func (p *testPeer) expectNothing(t *testing.T) {
+	t.Helper()
+
+	select {
+	case packet := <-p.received:
+		t.Errorf("%s got unexpected %s", p.client.InstanceID, packet.Opcode)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestRelayForwarding(t *testing.T) {
+	state, r, host, member := newTestLobby(t)
+
+	hostPeer := connect(t, state, r, host)
+	memberPeer := connect(t, state, r, member)
+	waitConnected(t, r, host, member)
+
+	// G_ opcodes go to everyone else
+	hostPeer.send(t, "G_MSG", structs.RelayMessage{Payload: "hello"})
+	memberPeer.expect(t, "G_MSG", host.InstanceID, "hello")
+	hostPeer.expectNothing(t)
+
+	// P_ opcodes only go to the recipient, and the origin can't be forged
+	memberPeer.send(t, "P_MSG", structs.RelayMessage{Origin: host.InstanceID, Recipient: host.InstanceID, Payload: "hi"})
+	hostPeer.expect(t, "P_MSG", member.InstanceID, "hi")
+	memberPeer.expectNothing(t)
+
+	// Unknown opcodes are not forwarded
+	hostPeer.send(t, "NOPE", structs.RelayMessage{Payload: "ignored"})
+	memberPeer.expectNothing(t)
+
+	if got := r.BytesOut.Load(); got == 0 {
+		t.Error("BytesOut = 0, want the forwarded bytes to be counted")
+	}
+}
+
+func TestUnauthorizedPeer(t *testing.T) {
+	state, r, host, member := newTestLobby(t)
+
+	hostPeer := connect(t, state, r, host)
+	memberPeer := connect(t, state, r, member)
+	waitConnected(t, r, host, member)
+
+	intruder := &structs.Client{InstanceID: "intruder", GameID: "game"}
+	intruderPeer := connectWithTicket(t, state, r, intruder, "forged")
+	raw, _ := json.Marshal(structs.Packet{Opcode: "G_MSG", Payload: structs.RelayMessage{Payload: "sneaky"}})
+
+	// A message that arrives before the ticket has been checked is dropped
+	r.Lock.Lock()
+	pending := r.Peers[intruder.InstanceID]
+	r.Lock.Unlock()
+	if pending == nil {
+		t.Fatal("relay has no peer for the intruder's offer")
+	}
+	handleMessage(r, pending, raw)
+
+	// So is one sent once the data channel is open, while the relay refuses the ticket
+	select {
+	case <-intruderPeer.opened:
+	case <-time.After(10 * time.Second):
+		t.Fatal("intruder's data channel did not open in time")
+	}
+
+	// Sending may fail once the relay has hung up, which is fine
+	intruderPeer.channel.SendText(string(raw))
+
+	hostPeer.expectNothing(t)
+	memberPeer.expectNothing(t)
+	if got := r.BytesIn.Load(); got != 0 {
+		t.Errorf("BytesIn = %d, want the intruder's bytes not to be counted", got)
+	}
+}
+
+func TestOfferAfterClose(t *testing.T) {
+	state, r, host, _ := newTestLobby(t)
+
+	r.Close <- true
+	<-r.CloseDone
+
+	conn, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatalf("NewPeerConnection() error = %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.CreateDataChannel("relay", nil); err != nil {
+		t.Fatalf("CreateDataChannel() error = %v", err)
+	}
+	offer, err := conn.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("CreateOffer() error = %v", err)
+	}
+
+	ticket := IssueTicket(state.RelaySecret, host, state.Lobbies["game"]["lobby"], time.Minute).Ticket
+	_, err = Offer(state, r, host, offer, ticket, func(webrtc.ICECandidateInit) {})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("Offer() error = %v, want %v", err, ErrClosed)
+	}
+
+	r.Lock.Lock()
+	defer r.Lock.Unlock()
+	if len(r.Peers) != 0 {
+		t.Errorf("relay has %d peers after closing, want 0", len(r.Peers))
+	}
+}
+
+// closed returns true if the relay has been closed.
+func closed(r *structs.Relay) bool {
+	r.Lock.Lock()
+	defer r.Lock.Unlock()
+	return r.Closed
+}
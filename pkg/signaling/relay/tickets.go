@@ -0,0 +1,45 @@
+package relay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// IssueTicket signs a ticket that lets the client connect to the relay of the given
+// lobby until the ticket expires.
+func IssueTicket(secret []byte, c *structs.Client, lobby *structs.Lobby, ttl time.Duration) structs.RelayTicket {
+	expires := time.Now().Add(ttl).Unix()
+	return structs.RelayTicket{
+		Relay:     lobby.RelayKey,
+		Ticket:    strconv.FormatInt(expires, 10) + "." + sign(secret, c.InstanceID, lobby.GameID, lobby.Name, lobby.RelayKey, expires),
+		ExpiresAt: expires * 1000,
+	}
+}
+
+// VerifyTicket returns true if the ticket was issued to the client for the relay
+// and has not yet expired.
+func VerifyTicket(secret []byte, c *structs.Client, r *structs.Relay, ticket string) bool {
+	expiry, signature, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return false
+	}
+	expected := sign(secret, c.InstanceID, r.GameID, r.Lobby, r.Id, expires)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// sign binds a ticket to the peer, the lobby, the relay and the expiry time.
+func sign(secret []byte, instanceID string, gameID string, lobby string, relayID string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{instanceID, gameID, lobby, relayID, strconv.FormatInt(expires, 10)}, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"slices"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Join admits a group of clients into the lobby together, as players or as spectators.
+// Either there is room for the whole group and everyone is admitted, or nobody is.
+// Nobody in the group may be in a lobby already.
+//
+// Returns the reason the group can't be admitted, or an empty string on success.
+func Join(state *structs.Server, lobby *structs.Lobby, group []*structs.Client, spectate bool) string {
+	lobby.Lock.Lock()
+	defer lobby.Lock.Unlock()
+	return join(state, lobby, group, spectate)
+}
+
+// join is the implementation of Join. The caller must hold the lobby's join lock.
+func join(state *structs.Server, lobby *structs.Lobby, group []*structs.Client, spectate bool) string {
+	// Check and admit everyone under the same lock, so nobody can join another lobby in between
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	// The lobby may have been destroyed, or started closing, while we were waiting for the lock
+	if state.Lobbies[lobby.GameID][lobby.Name] != lobby || lobby.Closing {
+		return "not found"
+	}
+	for i, c := range group {
+		if c.State == 0 {
+			continue
+		}
+		if i > 0 {
+			return "party busy"
+		}
+		if c.State < 0 {
+			return "disconnected"
+		}
+		return "already in a lobby"
+	}
+	if reason := Joinable(lobby, spectate); reason != "" {
+		return reason
+	}
+	if reason := Vacancy(lobby, spectate, group); reason != "" {
+		return reason
+	}
+
+	for _, c := range group {
+		admit(state, lobby, c, spectate)
+	}
+	return ""
+}
+
+// admit moves the client into the lobby as a member (or as a spectator) and
+// introduces it to the host, the other peers and the lobby's current state.
+// The caller must hold the state lock, and is responsible for checking that the
+// client may join.
+func admit(state *structs.Server, lobby *structs.Lobby, c *structs.Client, spectate bool) {
+
+	// Set the client as a member, or as a spectator
+	c.Lock.Lock()
+	if spectate {
+		updateState(state, lobby, c, 3)
+	} else {
+		updateState(state, lobby, c, 2)
+	}
+	c.Lock.Unlock()
+	message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "ok"})
+
+	if lobby.Host != nil {
+
+		// Tell the peer about the current host
+		message.Send(c, structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
+			UserID:     lobby.Host.UserID,
+			InstanceID: lobby.Host.InstanceID,
+			PublicKey:  lobby.Host.PublicKey,
+			Username:   lobby.Host.Name,
+		}})
+
+		// Tell the host and other peers about the new client
+		message.Send(lobby.Host, structs.Packet{Opcode: "NEW_PEER", Payload: structs.NewPeer{
+			UserID:     c.UserID,
+			InstanceID: c.InstanceID,
+			PublicKey:  c.PublicKey,
+			Username:   c.Name,
+			Spectator:  spectate,
+		}})
+	}
+
+	// Tell existing members and spectators about the new peer
+	message.Broadcast(Without(slices.Concat(lobby.Clients, lobby.Spectators), c), structs.Packet{Opcode: "PEER_JOIN", Payload: structs.NewPeer{
+		UserID:     c.UserID,
+		InstanceID: c.InstanceID,
+		PublicKey:  c.PublicKey,
+		Username:   c.Name,
+		Spectator:  spectate,
+	}})
+
+	// Give players a team and tell the peer about the current roster
+	if !spectate && autoAssign(lobby, c) {
+		BroadcastRoster(lobby)
+	} else if len(lobby.Teams) > 0 {
+		message.Send(c, structs.Packet{Opcode: "TEAM_ROSTER", Payload: lobby.Teams})
+	}
+
+	// Tell the peer about the relay (if present)
+	sendRelay(state, lobby, c)
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Lobby capacity is counted the same way everywhere:
+//
+//   - MaxPlayers limits the players, which are the host and the members.
+//     Dedicated hosts aren't players. -1 means unlimited.
+//   - MaxSpectators limits the spectators separately. 0 disables spectating and
+//     -1 means unlimited.
+//   - Reserved player slots count against MaxPlayers for everyone but the users
+//     they are held for.
+
+// Players returns the number of players in the lobby, including the host unless
+// it is a dedicated host.
+func Players(lobby *structs.Lobby) int {
+	return len(Roster(lobby))
+}
+
+// Roster returns the players in the lobby, including the host unless it is a
+// dedicated host.
+func Roster(lobby *structs.Lobby) []*structs.Client {
+	if lobby.Host == nil || lobby.Dedicated {
+		return lobby.Clients
+	}
+	return And(lobby.Clients, lobby.Host)
+}
+
+// ValidMaxPlayers checks a player limit for a new lobby. A host that plays takes
+// the first slot, so a limited lobby needs room for at least one player.
+func ValidMaxPlayers(maxPlayers int64) bool {
+	return maxPlayers == -1 || maxPlayers >= 1
+}
+
+// ValidMaxSpectators checks a spectator limit.
+func ValidMaxSpectators(maxSpectators int64) bool {
+	return maxSpectators >= -1
+}
+
+// Resizable checks whether the lobby's player limit can be changed to maxPlayers
+// without dropping anyone who is playing or holds a reservation.
+//
+// Returns the reason the limit can't be changed, or an empty string if it can.
+func Resizable(lobby *structs.Lobby, maxPlayers int64) string {
+	if !ValidMaxPlayers(maxPlayers) {
+		return "value error: argument (max players) should be -1 (unlimited) or at least 1"
+	}
+	if maxPlayers == -1 {
+		return ""
+	}
+	if int64(Players(lobby)) > maxPlayers {
+		return "value error: new size is smaller than the current number of players in the lobby"
+	}
+	if int64(Players(lobby)+held(lobby, nil, time.Now())) > maxPlayers {
+		return "value error: new size is smaller than the current number of players and reserved slots in the lobby"
+	}
+	return ""
+}
+
+// Vacancy checks whether the lobby has room for the given group of players, or of
+// spectators if spectate is set. Player slots reserved for someone outside of the
+// group are not available to it. An empty group checks for room for one more player
+// without a reservation.
+//
+// Returns the reason the clients can't be admitted, or an empty string if there is room.
+func Vacancy(lobby *structs.Lobby, spectate bool, group []*structs.Client) string {
+	count := max(len(group), 1)
+
+	if spectate {
+
+		// Check if the lobby permits spectators (ignore if lobby.MaxSpectators == -1)
+		if lobby.MaxSpectators == 0 {
+			return "spectating disabled"
+		}
+		if lobby.MaxSpectators != -1 && int64(len(lobby.Spectators)+count) > lobby.MaxSpectators {
+			return "full"
+		}
+		return ""
+	}
+
+	// Check if the lobby is full (ignore if lobby.MaxPlayers == -1)
+	if lobby.MaxPlayers == -1 {
+		return ""
+	}
+	if int64(Players(lobby)+count) > lobby.MaxPlayers {
+		return "full"
+	}
+
+	// Check if the remaining slots are reserved for someone else
+	if int64(Players(lobby)+held(lobby, group, time.Now())+count) > lobby.MaxPlayers {
+		return "reserved"
+	}
+	return ""
+}
@@ -0,0 +1,295 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// peers returns clients with user IDs u1, u2, ... starting at the given number.
+func peers(from int, count int) []*structs.Client {
+	clients := make([]*structs.Client, 0, count)
+	for i := from; i < from+count; i++ {
+		clients = append(clients, &structs.Client{
+			UserID:     fmt.Sprintf("u%d", i),
+			InstanceID: fmt.Sprintf("i%d", i),
+		})
+	}
+	return clients
+}
+
+// reserved returns reservations for the given users that expire after the given duration.
+func reserved(expiresIn time.Duration, users ...string) map[string]time.Time {
+	reservations := make(map[string]time.Time)
+	for _, id := range users {
+		reservations[id] = time.Now().Add(expiresIn)
+	}
+	return reservations
+}
+
+func TestPlayers(t *testing.T) {
+	host := peers(0, 1)[0]
+
+	tests := []struct {
+		name  string
+		lobby *structs.Lobby
+		want  int
+	}{
+		{"empty", &structs.Lobby{}, 0},
+		{"playing host only", &structs.Lobby{Host: host}, 1},
+		{"playing host and members", &structs.Lobby{Host: host, Clients: peers(1, 2)}, 3},
+		{"dedicated host only", &structs.Lobby{Host: host, Dedicated: true}, 0},
+		{"dedicated host and members", &structs.Lobby{Host: host, Dedicated: true, Clients: peers(1, 2)}, 2},
+		{"spectators are not players", &structs.Lobby{Host: host, Spectators: peers(1, 3)}, 1},
+		{"members without a host", &structs.Lobby{Clients: peers(1, 2)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Players(tt.lobby); got != tt.want {
+				t.Errorf("Players() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVacancy(t *testing.T) {
+	host := peers(0, 1)[0]
+
+	tests := []struct {
+		name     string
+		lobby    *structs.Lobby
+		spectate bool
+		group    []*structs.Client
+		want     string
+	}{
+		{
+			name:  "unlimited",
+			lobby: &structs.Lobby{Host: host, Clients: peers(1, 50), MaxPlayers: -1},
+			group: peers(100, 10),
+			want:  "",
+		},
+		{
+			name:  "unlimited ignores reservations",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: -1, Reservations: reserved(time.Minute, "u8", "u9")},
+			group: peers(1, 1),
+			want:  "",
+		},
+		{
+			name:  "room left",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 2},
+			group: peers(1, 1),
+			want:  "",
+		},
+		{
+			name:  "playing host takes a slot",
+			lobby: &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 2},
+			group: peers(2, 1),
+			want:  "full",
+		},
+		{
+			name:  "dedicated host takes no slot",
+			lobby: &structs.Lobby{Host: host, Dedicated: true, Clients: peers(1, 1), MaxPlayers: 2},
+			group: peers(2, 1),
+			want:  "",
+		},
+		{
+			name:  "dedicated lobby full of members",
+			lobby: &structs.Lobby{Host: host, Dedicated: true, Clients: peers(1, 2), MaxPlayers: 2},
+			group: peers(3, 1),
+			want:  "full",
+		},
+		{
+			name:  "spectators take no player slot",
+			lobby: &structs.Lobby{Host: host, Spectators: peers(1, 5), MaxPlayers: 2},
+			group: peers(6, 1),
+			want:  "",
+		},
+		{
+			name:  "empty group checks for one player",
+			lobby: &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 2},
+			want:  "full",
+		},
+		{
+			name:     "spectating disabled",
+			lobby:    &structs.Lobby{Host: host, MaxPlayers: 8, MaxSpectators: 0},
+			spectate: true,
+			group:    peers(1, 1),
+			want:     "spectating disabled",
+		},
+		{
+			name:     "spectators full",
+			lobby:    &structs.Lobby{Host: host, Spectators: peers(1, 1), MaxSpectators: 1},
+			spectate: true,
+			group:    peers(2, 1),
+			want:     "full",
+		},
+		{
+			name:     "spectators unlimited",
+			lobby:    &structs.Lobby{Host: host, Spectators: peers(1, 20), MaxSpectators: -1},
+			spectate: true,
+			group:    peers(30, 1),
+			want:     "",
+		},
+		{
+			name:     "spectators ignore full player slots",
+			lobby:    &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 2, MaxSpectators: 1},
+			spectate: true,
+			group:    peers(2, 1),
+			want:     "",
+		},
+		{
+			name:  "slot reserved for someone else",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 2, Reservations: reserved(time.Minute, "u9")},
+			group: peers(1, 1),
+			want:  "reserved",
+		},
+		{
+			name:  "room besides the reserved slot",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 3, Reservations: reserved(time.Minute, "u9")},
+			group: peers(1, 1),
+			want:  "",
+		},
+		{
+			name:  "reserving user joins their own slot",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 2, Reservations: reserved(time.Minute, "u1")},
+			group: peers(1, 1),
+			want:  "",
+		},
+		{
+			name:  "expired reservation is ignored",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 2, Reservations: reserved(-time.Minute, "u9")},
+			group: peers(1, 1),
+			want:  "",
+		},
+		{
+			name:  "party fits",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 4},
+			group: peers(1, 3),
+			want:  "",
+		},
+		{
+			name:  "party overflows",
+			lobby: &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 4},
+			group: peers(2, 3),
+			want:  "full",
+		},
+		{
+			name:  "party uses its own reservations",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 3, Reservations: reserved(time.Minute, "u1", "u2")},
+			group: peers(1, 2),
+			want:  "",
+		},
+		{
+			name:  "party overflows into someone else's reservation",
+			lobby: &structs.Lobby{Host: host, MaxPlayers: 3, Reservations: reserved(time.Minute, "u9")},
+			group: peers(1, 2),
+			want:  "reserved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Vacancy(tt.lobby, tt.spectate, tt.group); got != tt.want {
+				t.Errorf("Vacancy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResizable(t *testing.T) {
+	host := peers(0, 1)[0]
+
+	tests := []struct {
+		name       string
+		lobby      *structs.Lobby
+		maxPlayers int64
+		want       string
+	}{
+		{
+			name:       "unlimited",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 5), MaxPlayers: 6},
+			maxPlayers: -1,
+			want:       "",
+		},
+		{
+			name:       "invalid size",
+			lobby:      &structs.Lobby{Host: host},
+			maxPlayers: 0,
+			want:       "value error: argument (max players) should be -1 (unlimited) or at least 1",
+		},
+		{
+			name:       "exactly the players",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 2), MaxPlayers: 8},
+			maxPlayers: 3,
+			want:       "",
+		},
+		{
+			name:       "smaller than the players",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 2), MaxPlayers: 8},
+			maxPlayers: 2,
+			want:       "value error: new size is smaller than the current number of players in the lobby",
+		},
+		{
+			name:       "dedicated host doesn't count",
+			lobby:      &structs.Lobby{Host: host, Dedicated: true, Clients: peers(1, 2), MaxPlayers: 8},
+			maxPlayers: 2,
+			want:       "",
+		},
+		{
+			name:       "spectators don't count",
+			lobby:      &structs.Lobby{Host: host, Spectators: peers(1, 4), MaxPlayers: 8},
+			maxPlayers: 1,
+			want:       "",
+		},
+		{
+			name:       "smaller than the players and reservations",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 8, Reservations: reserved(time.Minute, "u9")},
+			maxPlayers: 2,
+			want:       "value error: new size is smaller than the current number of players and reserved slots in the lobby",
+		},
+		{
+			name:       "room for the players and reservations",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 8, Reservations: reserved(time.Minute, "u9")},
+			maxPlayers: 3,
+			want:       "",
+		},
+		{
+			name:       "expired reservations don't count",
+			lobby:      &structs.Lobby{Host: host, Clients: peers(1, 1), MaxPlayers: 8, Reservations: reserved(-time.Minute, "u9")},
+			maxPlayers: 2,
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resizable(tt.lobby, tt.maxPlayers); got != tt.want {
+				t.Errorf("Resizable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidMaxPlayers(t *testing.T) {
+	tests := []struct {
+		maxPlayers int64
+		want       bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, false},
+		{1, true},
+		{64, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.maxPlayers), func(t *testing.T) {
+			if got := ValidMaxPlayers(tt.maxPlayers); got != tt.want {
+				t.Errorf("ValidMaxPlayers(%d) = %t, want %t", tt.maxPlayers, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// AllowChat applies the chat rate limit to the client's user within its game, so that
+// opening several connections doesn't raise the limit. Returns true, and counts the
+// message toward the limit, if the user may send another message.
+func AllowChat(state *structs.Server, c *structs.Client) bool {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if state.ChatHistory[c.GameID] == nil {
+		state.ChatHistory[c.GameID] = make(map[string][]time.Time)
+	}
+	history := state.ChatHistory[c.GameID]
+
+	now := time.Now()
+	recent := pruneChat(history[c.UserID], now)
+	if len(recent) >= constants.CHAT_RATE_LIMIT {
+		history[c.UserID] = recent
+		return false
+	}
+	history[c.UserID] = append(recent, now)
+	return true
+}
+
+// pruneChat forgets messages that have fallen out of the rate limit window.
+func pruneChat(history []time.Time, now time.Time) []time.Time {
+	recent := make([]time.Time, 0, len(history))
+	for _, sent := range history {
+		if now.Sub(sent) < constants.CHAT_RATE_WINDOW {
+			recent = append(recent, sent)
+		}
+	}
+	return recent
+}
+
+// pruneChatHistory drops users that haven't chatted within the rate limit window,
+// and games where nobody has. The caller must hold the state lock.
+func pruneChatHistory(state *structs.Server, now time.Time) {
+	for gameID, users := range state.ChatHistory {
+		for id, history := range users {
+			if recent := pruneChat(history, now); len(recent) == 0 {
+				delete(users, id)
+			} else {
+				users[id] = recent
+			}
+		}
+		if len(users) == 0 {
+			delete(state.ChatHistory, gameID)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package session
+
+import (
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Millis converts a time to unix milliseconds, keeping sub-millisecond precision
+// for clock synchronization.
+func Millis(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1000
+}
+
+// MatchClock returns how far the client's lobby is into its match at the given time,
+// or false if the client isn't in a lobby whose match has started.
+func MatchClock(state *structs.Server, c *structs.Client, now time.Time) (time.Duration, bool) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	lobby := state.Lobbies[c.GameID][c.Lobby]
+	if lobby == nil || lobby.MatchStartedAt.IsZero() {
+		return 0, false
+	}
+	return now.Sub(lobby.MatchStartedAt), true
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// StartCountdown begins a server-timed countdown for the given lobby, which moves it
+// to the starting phase. Once it elapses, the match is in progress and MATCH_START is
+// broadcast with the shared start time.
+//
+// Returns the reason the countdown couldn't be started, or an empty string on success.
+func StartCountdown(state *structs.Server, lobby *structs.Lobby, length time.Duration) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if lobby.Countdown != nil {
+		return "already starting"
+	}
+	if reason := setPhase(state, lobby, constants.PHASE_STARTING); reason != "" {
+		return reason
+	}
+
+	cancel := make(chan bool)
+	lobby.Countdown = cancel
+	startTime := time.Now().Add(length)
+
+	log.Infof("Lobby %s match will start in %s", lobby.Name, length)
+	go runCountdown(state, lobby, cancel, startTime)
+	return ""
+}
+
+// CancelCountdown stops the countdown of the given lobby, if one is running, and
+// returns the lobby to the waiting phase.
+func CancelCountdown(state *structs.Server, lobby *structs.Lobby) bool {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+	return cancelCountdown(state, lobby)
+}
+
+// cancelCountdown is the lock-free implementation of CancelCountdown.
+func cancelCountdown(state *structs.Server, lobby *structs.Lobby) bool {
+	if lobby.Countdown == nil {
+		return false
+	}
+	close(lobby.Countdown)
+	lobby.Countdown = nil
+	setPhase(state, lobby, constants.PHASE_WAITING)
+	return true
+}
+
+func runCountdown(state *structs.Server, lobby *structs.Lobby, cancel chan bool, startTime time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	finish := time.NewTimer(time.Until(startTime) - constants.MATCH_START_LEAD)
+	defer finish.Stop()
+
+	broadcastCountdown(audienceOf(state, lobby), startTime)
+	for {
+		select {
+		case <-cancel:
+			log.Infof("Lobby %s match start countdown was cancelled", lobby.Name)
+			message.Broadcast(audienceOf(state, lobby), structs.Packet{Opcode: "COUNTDOWN_CANCELLED"})
+			return
+
+		case <-ticker.C:
+			broadcastCountdown(audienceOf(state, lobby), startTime)
+
+		case <-finish.C:
+			state.Lock.Lock()
+			started := func() bool {
+				defer state.Lock.Unlock()
+
+				// The countdown may have been cancelled while we were waiting for the lock
+				if lobby.Countdown != cancel {
+					return false
+				}
+				lobby.Countdown = nil
+				lobby.Locked = true
+				lobby.MatchStartedAt = startTime
+				setPhase(state, lobby, constants.PHASE_IN_PROGRESS)
+				return true
+			}()
+
+			if started {
+				log.Infof("Lobby %s match has started", lobby.Name)
+				message.Broadcast(audienceOf(state, lobby), structs.Packet{Opcode: "MATCH_START", Payload: structs.MatchStart{
+					StartTime:  startTime.UnixMilli(),
+					ServerTime: Millis(time.Now()),
+				}})
+			}
+			return
+		}
+	}
+}
+
+// audienceOf returns the audience of a lobby while holding the state read lock,
+// for use by goroutines that run outside of a client's message loop.
+func audienceOf(state *structs.Server, lobby *structs.Lobby) []*structs.Client {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+	return Audience(lobby)
+}
+
+func broadcastCountdown(peers []*structs.Client, startTime time.Time) {
+	remaining := time.Until(startTime).Round(time.Second)
+	if remaining <= 0 {
+		return
+	}
+	message.Broadcast(peers, structs.Packet{Opcode: "COUNTDOWN", Payload: structs.CountdownEvent{
+		Remaining: int64(remaining / time.Second),
+		StartTime: startTime.UnixMilli(),
+	}})
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// ValidHostKey returns true if the given API key lets a client register as a
+// dedicated host of the game.
+func ValidHostKey(state *structs.Server, gameID string, key string) bool {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	settings := state.GameSettings[gameID]
+	if settings == nil || settings.HostKey == "" || key == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(settings.HostKey), []byte(key)) == 1
+}
+
+// HostDedicated makes the dedicated host the owner of a newly created lobby. Unlike
+// other hosts, it stays uninitialized so that it can go on to host other lobbies.
+func HostDedicated(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	lobby.Host = c
+	lobby.Dedicated = true
+	publishLobby(state, lobby)
+}
+
+// HostedLobby returns the lobby the client is the host of, or nil if it isn't hosting.
+// Dedicated hosts may host several lobbies, so they have to name the one they mean.
+func HostedLobby(state *structs.Server, c *structs.Client, name string) *structs.Lobby {
+	if !c.Dedicated {
+		if c.State != 1 {
+			return nil
+		}
+		return state.Lobbies[c.GameID][c.Lobby]
+	}
+
+	lobby := state.Lobbies[c.GameID][name]
+	if lobby == nil || lobby.Host != c {
+		return nil
+	}
+	return lobby
+}
+
+// releaseLobbies closes every lobby hosted by a dedicated host that is leaving.
+// The caller must hold the state lock.
+func releaseLobbies(state *structs.Server, c *structs.Client) {
+	for _, lobby := range state.Lobbies[c.GameID] {
+		if lobby.Host == c {
+			log.Debugf("Lobby %s is closing since its dedicated host has left\n", lobby.Name)
+			message.Broadcast(Without(Audience(lobby), c), structs.Packet{Opcode: "PEER_LEFT", Payload: c.InstanceID})
+			releaseLobby(state, lobby)
+		}
+	}
+}
+
+// releaseLobby returns everyone in a dedicated lobby to the uninitialized state and
+// destroys it. The caller must hold the state lock.
+func releaseLobby(state *structs.Server, lobby *structs.Lobby) {
+	cancelCountdown(state, lobby)
+	lobby.Host = nil
+	evict(state, lobby.Clients)
+	lobby.Clients = nil
+	destroyLobby(state, lobby)
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Invite codes avoid characters that are easily confused with each other (0/O, 1/I/L).
+const inviteAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// ValidVisibility returns true if the given lobby visibility is known.
+// An empty visibility is valid and means public.
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case "",
+		constants.VISIBILITY_PUBLIC,
+		constants.VISIBILITY_UNLISTED,
+		constants.VISIBILITY_INVITE_ONLY:
+		return true
+	}
+	return false
+}
+
+// IsListed returns true if the lobby should appear in lobby listings and announcements.
+func IsListed(lobby *structs.Lobby) bool {
+	return lobby.Visibility == "" || lobby.Visibility == constants.VISIBILITY_PUBLIC
+}
+
+// FindInvite returns the lobby of the given game that issued the invite code, or nil.
+func FindInvite(state *structs.Server, gameID string, code string) *structs.Lobby {
+	if code == "" {
+		return nil
+	}
+	for _, lobby := range state.Lobbies[gameID] {
+		if lobby.Invites[code] {
+			return lobby
+		}
+	}
+	return nil
+}
+
+// CreateInvite generates a new invite code that is unique within the game and
+// registers it with the lobby.
+//
+// Returns the code, or the reason no code could be created.
+func CreateInvite(state *structs.Server, lobby *structs.Lobby) (string, string) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if len(lobby.Invites) >= constants.MAX_INVITES {
+		return "", "value error: too many active invites"
+	}
+
+	size := big.NewInt(int64(len(inviteAlphabet)))
+	for {
+		code := make([]byte, constants.INVITE_CODE_LENGTH)
+		for i := range code {
+
+			// Pick each character uniformly, which taking a random byte modulo the alphabet size wouldn't
+			n, err := rand.Int(rand.Reader, size)
+			if err != nil {
+				return "", err.Error()
+			}
+			code[i] = inviteAlphabet[n.Int64()]
+		}
+
+		if FindInvite(state, lobby.GameID, string(code)) == nil {
+			lobby.Invites[string(code)] = true
+			return string(code), ""
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// RunJanitor periodically closes lobbies that have been idle for too long or that
+// have outlived their game's maximum lobby lifetime, and releases expired slot
+// reservations. The host is warned before the lobby is closed. Blocks forever,
+// so it should be started in its own goroutine.
+func RunJanitor(state *structs.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		Sweep(state, time.Now())
+	}
+}
+
+// expiring is a lobby the janitor needs to act on.
+type expiring struct {
+	lobby    *structs.Lobby
+	reason   string
+	deadline time.Time
+}
+
+// Sweep performs a single janitor pass at the given time.
+func Sweep(state *structs.Server, now time.Time) {
+	warn := make([]expiring, 0)
+	expired := make([]expiring, 0)
+	released := make(map[*structs.Lobby][]string)
+
+	state.Lock.Lock()
+	pruneChatHistory(state, now)
+	for gameID, lobbies := range state.Lobbies {
+		for _, lobby := range lobbies {
+			if users := pruneReservations(lobby, now); len(users) > 0 {
+				released[lobby] = users
+				publishLobby(state, lobby)
+			}
+		}
+
+		settings := state.GameSettings[gameID]
+		if settings == nil {
+			continue
+		}
+
+		warning := settings.ExpiryWarning
+		if warning == 0 {
+			warning = constants.LOBBY_EXPIRY_WARNING
+		}
+
+		for _, lobby := range lobbies {
+			entry, ok := deadlineOf(lobby, settings)
+			if !ok {
+				continue
+			}
+
+			if !now.Before(entry.deadline) {
+				expired = append(expired, entry)
+			} else if lobby.ExpiryWarned != entry.reason && !now.Before(entry.deadline.Add(-warning)) {
+				lobby.ExpiryWarned = entry.reason
+				warn = append(warn, entry)
+			}
+		}
+	}
+	state.Lock.Unlock()
+
+	for lobby, users := range released {
+		for _, id := range users {
+			message.Send(lobby.Host, structs.Packet{Opcode: "RESERVATION_EXPIRED", Payload: id})
+		}
+		AdmitWaitlist(state, lobby)
+	}
+
+	for _, entry := range warn {
+		message.Send(entry.lobby.Host, structs.Packet{Opcode: "LOBBY_EXPIRING", Payload: structs.LobbyExpiry{
+			Reason:    entry.reason,
+			ExpiresAt: entry.deadline.UnixMilli(),
+		}})
+	}
+
+	for _, entry := range expired {
+		log.Infof("Lobby %s has expired (%s) and will be closed", entry.lobby.Name, entry.reason)
+		message.Broadcast(audienceOf(state, entry.lobby), structs.Packet{Opcode: "LOBBY_EXPIRED", Payload: entry.reason})
+		CloseLobby(state, entry.lobby)
+	}
+}
+
+// deadlineOf returns the earliest time at which the lobby expires under the given settings.
+func deadlineOf(lobby *structs.Lobby, settings *structs.GameSettings) (expiring, bool) {
+	var entry expiring
+	var found bool
+
+	if settings.LobbyIdleExpiry > 0 {
+		entry = expiring{lobby: lobby, reason: "idle", deadline: lobby.LastActivity.Add(settings.LobbyIdleExpiry)}
+		found = true
+	}
+
+	if settings.LobbyMaxLifetime > 0 {
+		deadline := lobby.CreatedAt.Add(settings.LobbyMaxLifetime)
+		if !found || deadline.Before(entry.deadline) {
+			entry = expiring{lobby: lobby, reason: "lifetime", deadline: deadline}
+			found = true
+		}
+	}
+
+	return entry, found
+}
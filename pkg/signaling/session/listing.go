@@ -0,0 +1,164 @@
+package session
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/latency"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Describe summarizes a lobby for FIND_ACK and LIST_ACK replies and lobby list subscriptions.
+func Describe(lobby *structs.Lobby) *structs.FindLobbyArgs {
+	info := &structs.FindLobbyArgs{
+		Name:              lobby.Name,
+		MaxPlayers:        lobby.MaxPlayers,
+		CurrentPlayers:    uint64(Players(lobby)),
+		ReservedSlots:     uint64(held(lobby, nil, time.Now())),
+		MaxSpectators:     lobby.MaxSpectators,
+		CurrentSpectators: uint64(len(lobby.Spectators)),
+		CurrentlyLocked:   lobby.Locked,
+		Phase:             lobby.Phase,
+		Backfill:          lobby.Backfill,
+		PasswordRequired:  lobby.Password != "",
+		RelayEnabled:      lobby.RelayEnabled,
+		Dedicated:         lobby.Dedicated,
+	}
+
+	if lobby.Host != nil {
+		info.Host = structs.NewPeer{
+			UserID:     lobby.Host.UserID,
+			InstanceID: lobby.Host.InstanceID,
+			PublicKey:  lobby.Host.PublicKey,
+			Username:   lobby.Host.Name,
+		}
+		info.HostLatency = latency.Of(lobby.Host).Milliseconds()
+	}
+
+	return info
+}
+
+// Matches returns true if a listed lobby satisfies the given subscription query.
+func Matches(lobby *structs.Lobby, query *structs.LobbyQuery) bool {
+	if !IsListed(lobby) {
+		return false
+	}
+	if query.Name != "" && !strings.Contains(strings.ToLower(lobby.Name), strings.ToLower(query.Name)) {
+		return false
+	}
+	if query.HasSpace && Vacancy(lobby, false, nil) != "" {
+		return false
+	}
+	if query.NoPassword && lobby.Password != "" {
+		return false
+	}
+	if query.Unlocked && lobby.Locked {
+		return false
+	}
+	if len(query.Phases) > 0 && !slices.Contains(query.Phases, lobby.Phase) {
+		return false
+	}
+	if query.Joinable && Joinable(lobby, false) != "" {
+		return false
+	}
+	return true
+}
+
+// List describes every lobby of the game that matches the query, for LIST_ACK replies.
+func List(state *structs.Server, gameID string, query *structs.LobbyQuery) []*structs.FindLobbyArgs {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	lobbies := make([]*structs.FindLobbyArgs, 0)
+	for _, lobby := range state.Lobbies[gameID] {
+		if Matches(lobby, query) {
+			lobbies = append(lobbies, Describe(lobby))
+		}
+	}
+	return lobbies
+}
+
+// Subscribe registers the client for lobby list updates matching the query, and
+// sends it a snapshot of every lobby that currently matches.
+func Subscribe(state *structs.Server, c *structs.Client, query *structs.LobbyQuery) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	c.Subscription = query
+	c.VisibleLobbies = make(map[string]bool)
+	state.Subscribers[c.GameID] = And(state.Subscribers[c.GameID], c)
+
+	snapshot := make([]*structs.FindLobbyArgs, 0)
+	for name, lobby := range state.Lobbies[c.GameID] {
+		if Matches(lobby, query) {
+			c.VisibleLobbies[name] = true
+			snapshot = append(snapshot, Describe(lobby))
+		}
+	}
+	message.Send(c, structs.Packet{Opcode: "LOBBY_SNAPSHOT", Payload: snapshot})
+}
+
+// Unsubscribe stops sending lobby list updates to the client.
+func Unsubscribe(state *structs.Server, c *structs.Client) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+	unsubscribe(state, c)
+}
+
+// PublishLobby pushes the current state of the lobby to every subscriber.
+func PublishLobby(state *structs.Server, lobby *structs.Lobby) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+	publishLobby(state, lobby)
+}
+
+// unsubscribe is the lock-free implementation of Unsubscribe.
+func unsubscribe(state *structs.Server, c *structs.Client) {
+	if c.Subscription == nil {
+		return
+	}
+	c.Subscription = nil
+	c.VisibleLobbies = nil
+	state.Subscribers[c.GameID] = Without(state.Subscribers[c.GameID], c)
+}
+
+// publishLobby is the lock-free implementation of PublishLobby.
+//
+// Subscribers that are seeing the lobby for the first time get NEW_LOBBY, those whose
+// query it no longer matches get LOBBY_REMOVED, and everyone who can see it gets LOBBY_UPDATED.
+func publishLobby(state *structs.Server, lobby *structs.Lobby) {
+	if len(state.Subscribers[lobby.GameID]) == 0 {
+		return
+	}
+
+	info := Describe(lobby)
+	for _, subscriber := range state.Subscribers[lobby.GameID] {
+		visible := subscriber.VisibleLobbies[lobby.Name]
+
+		if !Matches(lobby, subscriber.Subscription) {
+			if visible {
+				delete(subscriber.VisibleLobbies, lobby.Name)
+				message.Send(subscriber, structs.Packet{Opcode: "LOBBY_REMOVED", Payload: lobby.Name})
+			}
+			continue
+		}
+
+		if !visible {
+			subscriber.VisibleLobbies[lobby.Name] = true
+			message.Send(subscriber, structs.Packet{Opcode: "NEW_LOBBY", Payload: lobby.Name})
+		}
+		message.Send(subscriber, structs.Packet{Opcode: "LOBBY_UPDATED", Payload: info})
+	}
+}
+
+// publishClosed tells subscribers that could see the lobby that it has been destroyed.
+func publishClosed(state *structs.Server, lobby *structs.Lobby) {
+	for _, subscriber := range state.Subscribers[lobby.GameID] {
+		if subscriber.VisibleLobbies[lobby.Name] {
+			delete(subscriber.VisibleLobbies, lobby.Name)
+			message.Send(subscriber, structs.Packet{Opcode: "LOBBY_CLOSED", Payload: lobby.Name})
+		}
+	}
+}
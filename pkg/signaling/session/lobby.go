@@ -0,0 +1,73 @@
+package session
+
+import (
+	"slices"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// CloseLobby returns every peer and spectator in the lobby, and then the host, to the
+// uninitialized state. The lobby is destroyed once its host has left.
+func CloseLobby(state *structs.Server, lobby *structs.Lobby) {
+
+	// Dedicated hosts aren't in the lobby, so the lobby is simply destroyed
+	if lobby.Dedicated {
+		state.Lock.Lock()
+		defer state.Lock.Unlock()
+		if state.Lobbies[lobby.GameID][lobby.Name] == lobby {
+			releaseLobby(state, lobby)
+		}
+		return
+	}
+
+	// Stop letting clients in before anyone leaves, since every departure frees up room
+	lobby.Lock.Lock()
+	state.Lock.Lock()
+	lobby.Closing = true
+	dropJoinRequests(lobby)
+	dropWaitlist(lobby, "not found")
+	peers := slices.Concat(lobby.Clients, lobby.Spectators)
+	host := lobby.Host
+	state.Lock.Unlock()
+	lobby.Lock.Unlock()
+
+	for _, client := range peers {
+		UpdateState(state, lobby, client, 0)
+	}
+
+	if host != nil {
+		UpdateState(state, lobby, host, 0)
+	}
+}
+
+// LobbyOf returns the lobby the client is in (as the host, a member or a spectator),
+// or nil if it isn't in one. Dedicated hosts have to name the lobby they mean.
+func LobbyOf(state *structs.Server, c *structs.Client, name string) *structs.Lobby {
+	if c.Dedicated {
+		return HostedLobby(state, c, name)
+	}
+	if c.State < 1 {
+		return nil
+	}
+	return state.Lobbies[c.GameID][c.Lobby]
+}
+
+// Touch records activity in the lobby the client is in, postponing idle expiry.
+func Touch(state *structs.Server, c *structs.Client) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if lobby := state.Lobbies[c.GameID][c.Lobby]; lobby != nil {
+		touch(lobby)
+	}
+}
+
+// touch is the lock-free implementation of Touch. Only the idle deadline moves, so a
+// warning about the lobby's lifetime still stands.
+func touch(lobby *structs.Lobby) {
+	lobby.LastActivity = time.Now()
+	if lobby.ExpiryWarned == "idle" {
+		lobby.ExpiryWarned = ""
+	}
+}
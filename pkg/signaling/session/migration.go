@@ -0,0 +1,74 @@
+package session
+
+import (
+	"slices"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/latency"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// ValidMigrationPolicy returns true if the given host migration policy is known.
+// An empty policy is valid and means join order.
+func ValidMigrationPolicy(policy string) bool {
+	switch policy {
+	case "",
+		constants.MIGRATE_JOIN_ORDER,
+		constants.MIGRATE_LOWEST_LATENCY,
+		constants.MIGRATE_HIGHEST_UPTIME,
+		constants.MIGRATE_SUCCESSORS,
+		constants.MIGRATE_CLOSE:
+		return true
+	}
+	return false
+}
+
+// NextHost picks the member that should take over the lobby when the host leaves,
+// according to the lobby's migration policy.
+//
+// Returns nil if there are no members or if the lobby should be closed instead.
+func NextHost(lobby *structs.Lobby) *structs.Client {
+	if len(lobby.Clients) == 0 {
+		return nil
+	}
+
+	switch lobby.MigrationPolicy {
+	case constants.MIGRATE_CLOSE:
+		return nil
+
+	case constants.MIGRATE_LOWEST_LATENCY:
+		return slices.MinFunc(lobby.Clients, func(a, b *structs.Client) int {
+
+			// Members with an unknown latency are considered last. Only the server's own
+			// measurements count, since a client could claim any latency it likes.
+			la, lb := latency.Measured(a), latency.Measured(b)
+			switch {
+			case la == lb:
+				return 0
+			case la == 0:
+				return 1
+			case lb == 0:
+				return -1
+			case la < lb:
+				return -1
+			default:
+				return 1
+			}
+		})
+
+	case constants.MIGRATE_HIGHEST_UPTIME:
+		return slices.MinFunc(lobby.Clients, func(a, b *structs.Client) int {
+			return a.ConnectedAt.Compare(b.ConnectedAt)
+		})
+
+	case constants.MIGRATE_SUCCESSORS:
+		for _, id := range lobby.Successors {
+			if successor := Get(lobby.Clients, id); successor != nil {
+				return successor
+			}
+		}
+	}
+
+	// Join order
+	return lobby.Clients[0]
+}
@@ -0,0 +1,258 @@
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// CreateParty creates a new party led by the client.
+//
+// Returns the reason the party couldn't be created, or an empty string on success.
+func CreateParty(state *structs.Server, c *structs.Client) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if c.Party != "" {
+		return "already in a party"
+	}
+
+	party := &structs.Party{
+		ID:      ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String(),
+		Leader:  c,
+		Members: []*structs.Client{c},
+		Invites: make(map[string]bool),
+	}
+	if state.Parties[c.GameID] == nil {
+		state.Parties[c.GameID] = make(map[string]*structs.Party)
+	}
+	state.Parties[c.GameID][party.ID] = party
+	c.Party = party.ID
+
+	log.Debugf("Party %s created by %s", party.ID, c.InstanceID)
+	broadcastParty(party)
+	return ""
+}
+
+// InviteToParty invites a peer of the same game into the client's party.
+// Only the party leader may invite.
+//
+// Returns the reason the invite was refused, or an empty string on success.
+func InviteToParty(state *structs.Server, c *structs.Client, id string) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	party := state.Parties[c.GameID][c.Party]
+	if party == nil {
+		return "not in a party"
+	}
+	if party.Leader != c {
+		return "not the party leader"
+	}
+	if len(party.Members) >= constants.MAX_PARTY_SIZE {
+		return fmt.Sprintf("party full (maximum is %d)", constants.MAX_PARTY_SIZE)
+	}
+
+	peer := findClient(state, c.GameID, id)
+	if peer == nil || peer == c {
+		return "no peer found"
+	}
+
+	// Dedicated hosts own lobbies rather than joining them
+	if peer.Dedicated {
+		return "dedicated host"
+	}
+	if peer.Party == party.ID {
+		return "already in the party"
+	}
+
+	party.Invites[peer.InstanceID] = true
+	message.Send(peer, structs.Packet{Opcode: "PARTY_INVITE", Payload: structs.PartyInvite{
+		Party: party.ID,
+		Leader: structs.NewPeer{
+			UserID:     c.UserID,
+			InstanceID: c.InstanceID,
+			PublicKey:  c.PublicKey,
+			Username:   c.Name,
+		},
+	}})
+	return ""
+}
+
+// AcceptParty adds the client to a party it has been invited to.
+//
+// Returns the reason the client couldn't join, or an empty string on success.
+func AcceptParty(state *structs.Server, c *structs.Client, id string) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	party := state.Parties[c.GameID][id]
+	if party == nil || !party.Invites[c.InstanceID] {
+		return "no invite found"
+	}
+	if c.Party != "" {
+		return "already in a party"
+	}
+	if len(party.Members) >= constants.MAX_PARTY_SIZE {
+		return fmt.Sprintf("party full (maximum is %d)", constants.MAX_PARTY_SIZE)
+	}
+
+	delete(party.Invites, c.InstanceID)
+	party.Members = append(party.Members, c)
+	c.Party = party.ID
+
+	broadcastParty(party)
+	return ""
+}
+
+// DeclineParty turns down an invite and lets the party leader know.
+//
+// Returns the reason the invite couldn't be declined, or an empty string on success.
+func DeclineParty(state *structs.Server, c *structs.Client, id string) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	party := state.Parties[c.GameID][id]
+	if party == nil || !party.Invites[c.InstanceID] {
+		return "no invite found"
+	}
+
+	delete(party.Invites, c.InstanceID)
+	message.Send(party.Leader, structs.Packet{Opcode: "PARTY_INVITE_DECLINED", Payload: c.InstanceID})
+	return ""
+}
+
+// LeaveParty removes the client from its party.
+//
+// Returns the reason the client couldn't leave, or an empty string on success.
+func LeaveParty(state *structs.Server, c *structs.Client) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if c.Party == "" {
+		return "not in a party"
+	}
+	leaveParty(state, c)
+	return ""
+}
+
+// KickFromParty removes a member from the client's party. Only the party leader may kick.
+//
+// Returns the reason the member couldn't be kicked, or an empty string on success.
+func KickFromParty(state *structs.Server, c *structs.Client, id string) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	party := state.Parties[c.GameID][c.Party]
+	if party == nil {
+		return "not in a party"
+	}
+	if party.Leader != c {
+		return "not the party leader"
+	}
+
+	member := Get(party.Members, id)
+	if member == nil || member == c {
+		return "no member found"
+	}
+
+	leaveParty(state, member)
+	message.Send(member, structs.Packet{Opcode: "PARTY_KICKED", Payload: party.ID})
+	return ""
+}
+
+// Group returns the clients that should move along with the given client when it
+// joins a lobby: the whole party if the client leads one, or just the client otherwise.
+//
+// Returns "party busy" if a party member is already in a lobby, has disconnected, or
+// is a dedicated host.
+func Group(state *structs.Server, c *structs.Client) ([]*structs.Client, string) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	party := state.Parties[c.GameID][c.Party]
+	if party == nil || party.Leader != c {
+		return []*structs.Client{c}, ""
+	}
+
+	group := append([]*structs.Client{c}, Without(party.Members, c)...)
+	for _, member := range group[1:] {
+		if member.State != 0 || member.Dedicated {
+			return nil, "party busy"
+		}
+	}
+	return group, ""
+}
+
+// leaveParty is the lock-free implementation of LeaveParty. The party is handed to the
+// longest-standing member if the leader leaves, and disbanded once it is empty.
+func leaveParty(state *structs.Server, c *structs.Client) {
+	party := state.Parties[c.GameID][c.Party]
+	c.Party = ""
+	if party == nil {
+		return
+	}
+
+	party.Members = Without(party.Members, c)
+	if len(party.Members) == 0 {
+		delete(state.Parties[c.GameID], party.ID)
+		if len(state.Parties[c.GameID]) == 0 {
+			delete(state.Parties, c.GameID)
+		}
+		log.Debugf("Party %s disbanded", party.ID)
+		return
+	}
+
+	if party.Leader == c {
+		party.Leader = party.Members[0]
+		log.Debugf("Party %s is now led by %s", party.ID, party.Leader.InstanceID)
+	}
+	broadcastParty(party)
+}
+
+// dropPartyInvites forgets the invites a disconnecting client never answered.
+// The caller must hold the state lock.
+func dropPartyInvites(state *structs.Server, c *structs.Client) {
+	for _, party := range state.Parties[c.GameID] {
+		delete(party.Invites, c.InstanceID)
+	}
+}
+
+// findClient returns the client with the given instance ID, whether or not it is in
+// a lobby, or nil if it is not connected. The caller must hold the state lock.
+func findClient(state *structs.Server, gameID string, id string) *structs.Client {
+	if c := Get(state.UninitializedPeers[gameID], id); c != nil {
+		return c
+	}
+	for _, lobby := range state.Lobbies[gameID] {
+		if c := Get(Audience(lobby), id); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// broadcastParty sends the current party roster to every member.
+func broadcastParty(party *structs.Party) {
+	update := structs.PartyUpdate{
+		ID:      party.ID,
+		Leader:  party.Leader.InstanceID,
+		Members: make([]structs.NewPeer, 0, len(party.Members)),
+	}
+	for _, member := range party.Members {
+		update.Members = append(update.Members, structs.NewPeer{
+			UserID:     member.UserID,
+			InstanceID: member.InstanceID,
+			PublicKey:  member.PublicKey,
+			Username:   member.Name,
+		})
+	}
+	message.Broadcast(party.Members, structs.Packet{Opcode: "PARTY_UPDATE", Payload: update})
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// transitions lists the phases a lobby may move to from each phase.
+var transitions = map[string][]string{
+	constants.PHASE_WAITING:     {constants.PHASE_STARTING},
+	constants.PHASE_STARTING:    {constants.PHASE_WAITING, constants.PHASE_IN_PROGRESS},
+	constants.PHASE_IN_PROGRESS: {constants.PHASE_FINISHED, constants.PHASE_WAITING},
+	constants.PHASE_FINISHED:    {constants.PHASE_WAITING},
+}
+
+// ValidPhase returns true if the given lobby phase is known.
+func ValidPhase(phase string) bool {
+	_, ok := transitions[phase]
+	return ok
+}
+
+// CanTransition returns true if a lobby may move from one phase to the other.
+func CanTransition(from string, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// SetPhase moves the lobby to the given phase at the host's request. A running
+// match start countdown is cancelled first. The starting and in progress phases
+// are entered through the countdown, so they can't be set directly.
+//
+// Returns the reason the phase couldn't be changed, or an empty string on success.
+func SetPhase(state *structs.Server, lobby *structs.Lobby, phase string) string {
+	if !ValidPhase(phase) {
+		return "value error: unknown phase"
+	}
+	if phase == constants.PHASE_STARTING || phase == constants.PHASE_IN_PROGRESS {
+		return "use START to start the match"
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if !CanTransition(lobby.Phase, phase) {
+		return fmt.Sprintf("can't go from %s to %s", lobby.Phase, phase)
+	}
+
+	// Cancelling the countdown returns the lobby to the waiting phase
+	if cancelCountdown(state, lobby) {
+		return ""
+	}
+	return setPhase(state, lobby, phase)
+}
+
+// Joinable returns the reason the lobby's phase keeps a client from joining, or an
+// empty string if it may join. Spectators may join in any phase, while players may
+// only join a waiting lobby, or a match in progress if the lobby allows backfill.
+func Joinable(lobby *structs.Lobby, spectate bool) string {
+	if spectate {
+		return ""
+	}
+	switch lobby.Phase {
+	case constants.PHASE_STARTING:
+		return "starting"
+	case constants.PHASE_IN_PROGRESS:
+		if !lobby.Backfill {
+			return "in progress"
+		}
+	case constants.PHASE_FINISHED:
+		return "finished"
+	}
+	return ""
+}
+
+// setPhase is the lock-free implementation of SetPhase, which also allows the phases
+// entered through the countdown. Everyone in the lobby is told about the new phase.
+func setPhase(state *structs.Server, lobby *structs.Lobby, phase string) string {
+	if !CanTransition(lobby.Phase, phase) {
+		return fmt.Sprintf("can't go from %s to %s", lobby.Phase, phase)
+	}
+
+	log.Debugf("Lobby %s phase %s -> %s", lobby.Name, lobby.Phase, phase)
+	lobby.Phase = phase
+
+	// Votes on a match result only count for the match they were cast in
+	clear(lobby.ResultVotes)
+
+	// A new match gets a new clock
+	if phase == constants.PHASE_WAITING {
+		lobby.MatchStartedAt = time.Time{}
+	}
+
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "LOBBY_PHASE", Payload: phase})
+	publishLobby(state, lobby)
+	return ""
+}
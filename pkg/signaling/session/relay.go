@@ -0,0 +1,177 @@
+package session
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/relay"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// ValidConnectionStatus returns true if the given connection outcome is known.
+func ValidConnectionStatus(status string) bool {
+	switch status {
+	case constants.CONNECTION_CONNECTED,
+		constants.CONNECTION_FAILED,
+		constants.CONNECTION_TIMED_OUT:
+		return true
+	}
+	return false
+}
+
+// SharedLobby returns the lobby that both the client and the given peer are in,
+// along with the peer, or nil if they aren't in the same lobby. Dedicated hosts
+// are considered to be in every lobby they host.
+func SharedLobby(state *structs.Server, c *structs.Client, id string) (*structs.Lobby, *structs.Client) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	for _, lobby := range state.Lobbies[c.GameID] {
+		audience := Audience(lobby)
+		if !slices.Contains(audience, c) {
+			continue
+		}
+		if peer := Get(Without(audience, c), id); peer != nil {
+			return lobby, peer
+		}
+	}
+	return nil, nil
+}
+
+// ReportConnection records the outcome of a peer-to-peer connection attempt between
+// two peers in a lobby. If the attempt failed, the lobby's relay is started (or reused)
+// and both peers are told to connect through it instead.
+func ReportConnection(state *structs.Server, lobby *structs.Lobby, c *structs.Client, peer *structs.Client, status string) error {
+	state.Lock.Lock()
+	lobby.Connections[pairOf(c, peer)] = status
+	state.Lock.Unlock()
+
+	if status == constants.CONNECTION_CONNECTED {
+		return nil
+	}
+
+	log.Debugf("Lobby %s peers %s and %s could not connect (%s), falling back to the relay", lobby.Name, c.InstanceID, peer.InstanceID, status)
+	if _, err := EnableRelay(state, lobby, c); err != nil {
+		return err
+	}
+
+	SendRelay(state, lobby, c)
+	SendRelay(state, lobby, peer)
+	return nil
+}
+
+// SendRelay tells a peer about the lobby's relay, if it has one, along with a ticket
+// that lets it connect.
+func SendRelay(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+	sendRelay(state, lobby, c)
+}
+
+// sendRelay is the lock-free implementation of SendRelay. The caller must hold the state lock.
+func sendRelay(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
+	ticket, ok := renewTicket(state, lobby, c)
+	if !ok {
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "RELAY", Payload: ticket.Relay})
+	message.Send(c, structs.Packet{Opcode: "RELAY_TICKET", Payload: ticket})
+}
+
+// RenewTicket issues a new relay ticket to a peer in the lobby, for when its previous
+// ticket has expired. Returns false if the lobby has no relay or the client isn't in it.
+func RenewTicket(state *structs.Server, lobby *structs.Lobby, c *structs.Client) (structs.RelayTicket, bool) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+	return renewTicket(state, lobby, c)
+}
+
+// renewTicket is the lock-free implementation of RenewTicket. The caller must hold the state lock.
+func renewTicket(state *structs.Server, lobby *structs.Lobby, c *structs.Client) (structs.RelayTicket, bool) {
+	if !lobby.RelayEnabled || !slices.Contains(Audience(lobby), c) {
+		return structs.RelayTicket{}, false
+	}
+	return relay.IssueTicket(state.RelaySecret, c, lobby, constants.RELAY_TICKET_TTL), true
+}
+
+// EnableRelay starts the lobby's relay if it isn't running yet, and returns its peer ID.
+func EnableRelay(state *structs.Server, lobby *structs.Lobby, c *structs.Client) (string, error) {
+
+	// Hold the join lock so that only one relay is spawned per lobby
+	lobby.Lock.Lock()
+	defer lobby.Lock.Unlock()
+
+	state.Lock.RLock()
+	enabled, key := lobby.RelayEnabled, lobby.RelayKey
+	state.Lock.RUnlock()
+	if enabled {
+		return key, nil
+	}
+
+	r, err := relay.SpawnRelay(c, state, lobby.Name)
+	if err != nil {
+		return "", err
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	// The lobby may have been destroyed while the relay was starting
+	if state.Lobbies[lobby.GameID][lobby.Name] != lobby {
+		delete(state.Relays[lobby.GameID], lobby.Name)
+		go func() {
+			r.Close <- true
+			<-r.CloseDone
+		}()
+		return "", errors.New("lobby closed")
+	}
+
+	lobby.RelayEnabled = true
+	lobby.RelayKey = r.Id
+	publishLobby(state, lobby)
+	return r.Id, nil
+}
+
+// FindRelay returns the relay with the given peer ID, as long as it belongs to a
+// lobby the client is in. Returns nil otherwise.
+func FindRelay(state *structs.Server, c *structs.Client, id string) *structs.Relay {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	for _, lobby := range state.Lobbies[c.GameID] {
+		if lobby.RelayEnabled && lobby.RelayKey == id && slices.Contains(Audience(lobby), c) {
+			return state.Relays[c.GameID][lobby.Name]
+		}
+	}
+	return nil
+}
+
+// leaveRelay disconnects a peer that is leaving the lobby from the lobby's relay.
+// The caller must hold the state lock.
+func leaveRelay(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
+	if r := state.Relays[lobby.GameID][lobby.Name]; r != nil {
+		go relay.Disconnect(r, c)
+	}
+}
+
+// forgetConnections drops the reported connection outcomes of a peer that is
+// leaving the lobby. The caller must hold the state lock.
+func forgetConnections(lobby *structs.Lobby, c *structs.Client) {
+	for pair := range lobby.Connections {
+		if pair[0] == c.InstanceID || pair[1] == c.InstanceID {
+			delete(lobby.Connections, pair)
+		}
+	}
+}
+
+// pairOf returns the key for a pair of peers, regardless of their order.
+func pairOf(a *structs.Client, b *structs.Client) [2]string {
+	if a.InstanceID < b.InstanceID {
+		return [2]string{a.InstanceID, b.InstanceID}
+	}
+	return [2]string{b.InstanceID, a.InstanceID}
+}
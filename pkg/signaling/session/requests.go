@@ -0,0 +1,133 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// RequestJoin asks the lobby host to approve the client (and its party, if any) joining.
+// The client is told its request is pending, and is denied automatically if the host
+// does not answer within constants.JOIN_REQUEST_TIMEOUT.
+func RequestJoin(state *structs.Server, lobby *structs.Lobby, c *structs.Client, spectate bool, party []*structs.Client) {
+	state.Lock.Lock()
+	closing := func() bool {
+		defer state.Lock.Unlock()
+
+		if lobby.Closing {
+			return true
+		}
+
+		// Replace any request the client has pending elsewhere
+		withdrawJoin(state, c)
+
+		request := &structs.JoinRequest{Client: c, Spectate: spectate, Party: party}
+		request.Timer = time.AfterFunc(constants.JOIN_REQUEST_TIMEOUT, func() {
+			if takeJoin(state, lobby, c.InstanceID) == nil {
+				return
+			}
+			log.Debugf("Lobby %s join request from %s timed out", lobby.Name, c.InstanceID)
+			message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "timeout"})
+			message.Send(lobby.Host, structs.Packet{Opcode: "JOIN_REQUEST_EXPIRED", Payload: c.InstanceID})
+		})
+		lobby.JoinRequests[c.InstanceID] = request
+		c.PendingJoin = lobby.Name
+		return false
+	}()
+
+	if closing {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "not found"})
+		return
+	}
+
+	message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "pending"})
+	message.Send(lobby.Host, structs.Packet{Opcode: "JOIN_REQUEST", Payload: describeRequest(c, spectate, party)})
+}
+
+// ResolveJoin answers a pending join request. Approved clients are admitted if
+// there is still room, and denied clients are told so.
+//
+// Returns the reason the request couldn't be resolved, or an empty string on success.
+func ResolveJoin(state *structs.Server, lobby *structs.Lobby, id string, approve bool) string {
+	request := takeJoin(state, lobby, id)
+	if request == nil {
+		return "no request found"
+	}
+
+	if !approve {
+		message.Send(request.Client, structs.Packet{Opcode: "JOIN_ACK", Payload: "denied"})
+		return ""
+	}
+
+	// The whole party is admitted, or nobody is. Join also refuses a client that has
+	// disconnected or joined somewhere else in the meantime, and the client is told
+	// either way.
+	group := append([]*structs.Client{request.Client}, request.Party...)
+	if reason := Join(state, lobby, group, request.Spectate); reason != "" {
+		message.Send(request.Client, structs.Packet{Opcode: "JOIN_ACK", Payload: reason})
+		return reason
+	}
+	return ""
+}
+
+// describeRequest describes a join request to the lobby host.
+func describeRequest(c *structs.Client, spectate bool, party []*structs.Client) structs.NewPeer {
+	peer := structs.NewPeer{
+		UserID:     c.UserID,
+		InstanceID: c.InstanceID,
+		PublicKey:  c.PublicKey,
+		Username:   c.Name,
+		Spectator:  spectate,
+	}
+	for _, member := range party {
+		peer.Party = append(peer.Party, member.InstanceID)
+	}
+	return peer
+}
+
+// takeJoin removes a pending join request from the lobby and returns it,
+// or returns nil if there is no such request.
+func takeJoin(state *structs.Server, lobby *structs.Lobby, id string) *structs.JoinRequest {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	request := lobby.JoinRequests[id]
+	if request != nil {
+		request.Timer.Stop()
+		delete(lobby.JoinRequests, id)
+		request.Client.PendingJoin = ""
+	}
+	return request
+}
+
+// withdrawJoin cancels the client's pending join request, if it has one, and lets
+// the host know. The caller must hold the state lock.
+func withdrawJoin(state *structs.Server, c *structs.Client) {
+	if c.PendingJoin == "" {
+		return
+	}
+
+	if lobby := state.Lobbies[c.GameID][c.PendingJoin]; lobby != nil {
+		if request := lobby.JoinRequests[c.InstanceID]; request != nil {
+			request.Timer.Stop()
+			delete(lobby.JoinRequests, c.InstanceID)
+			message.Send(lobby.Host, structs.Packet{Opcode: "JOIN_REQUEST_CANCELLED", Payload: c.InstanceID})
+		}
+	}
+	c.PendingJoin = ""
+}
+
+// dropJoinRequests refuses every pending join request of a lobby that is being
+// destroyed. The caller must hold the state lock.
+func dropJoinRequests(lobby *structs.Lobby) {
+	for id, request := range lobby.JoinRequests {
+		request.Timer.Stop()
+		request.Client.PendingJoin = ""
+		message.Send(request.Client, structs.Packet{Opcode: "JOIN_ACK", Payload: "not found"})
+		delete(lobby.JoinRequests, id)
+	}
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Reserve holds player slots in the lobby for the given users, and for every member
+// of the given party, so that nobody else can take them before they join. The
+// reservations expire after the requested duration.
+//
+// Returns the reason the slots couldn't be reserved, or an empty string on success.
+func Reserve(state *structs.Server, lobby *structs.Lobby, args structs.ReserveArgs) string {
+	duration := constants.RESERVATION_DEFAULT
+	if args.Duration != 0 {
+		duration = time.Duration(args.Duration) * time.Second
+	}
+	if duration < 0 || duration > constants.RESERVATION_MAX {
+		return fmt.Sprintf("value error: duration should be between 1 and %d seconds", int64(constants.RESERVATION_MAX/time.Second))
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	users := slices.Clone(args.Users)
+	if args.Party != "" {
+		party := state.Parties[lobby.GameID][args.Party]
+		if party == nil {
+			return "no party found"
+		}
+		for _, member := range party.Members {
+			users = append(users, member.UserID)
+		}
+	}
+
+	// Users that are already playing don't need a slot held for them
+	now := time.Now()
+	fresh := make([]string, 0, len(users))
+	for _, id := range users {
+		if id == "" || slices.Contains(fresh, id) || isPlaying(lobby, id) {
+			continue
+		}
+		if expires, ok := lobby.Reservations[id]; ok && now.Before(expires) {
+			continue
+		}
+		fresh = append(fresh, id)
+	}
+
+	if lobby.MaxPlayers != -1 && int64(Players(lobby)+held(lobby, nil, now)+len(fresh)) > lobby.MaxPlayers {
+		return "not enough room"
+	}
+
+	// Renew existing reservations as well as adding new ones
+	expires := now.Add(duration)
+	for _, id := range users {
+		if id != "" && !isPlaying(lobby, id) {
+			lobby.Reservations[id] = expires
+		}
+	}
+
+	publishLobby(state, lobby)
+	return ""
+}
+
+// Unreserve releases the slot held for the given user.
+// Returns false if there was no reservation for the user.
+func Unreserve(state *structs.Server, lobby *structs.Lobby, id string) bool {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if _, ok := lobby.Reservations[id]; !ok {
+		return false
+	}
+	delete(lobby.Reservations, id)
+	publishLobby(state, lobby)
+	return true
+}
+
+// held returns how many player slots are reserved for users that are not in the
+// given group. The caller must hold the state lock.
+func held(lobby *structs.Lobby, group []*structs.Client, now time.Time) int {
+	count := 0
+	for id, expires := range lobby.Reservations {
+		if !now.Before(expires) {
+			continue
+		}
+		if slices.ContainsFunc(group, func(c *structs.Client) bool { return c.UserID == id }) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// pruneReservations removes every expired reservation and returns the users they
+// were held for. The caller must hold the state lock.
+func pruneReservations(lobby *structs.Lobby, now time.Time) []string {
+	expired := make([]string, 0)
+	for id, expires := range lobby.Reservations {
+		if !now.Before(expires) {
+			delete(lobby.Reservations, id)
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// isPlaying returns true if the user is the host or a member of the lobby.
+func isPlaying(lobby *structs.Lobby, id string) bool {
+	if lobby.Host != nil && lobby.Host.UserID == id {
+		return true
+	}
+	return slices.ContainsFunc(lobby.Clients, func(c *structs.Client) bool { return c.UserID == id })
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// SubmitResult takes a match result from the host, or a vote for one from a member.
+// The host's result is accepted straight away, while members' results are only
+// accepted once a majority of the players have submitted the same one. Either way,
+// the result must cover exactly the players in the lobby, on the teams they are
+// actually on, and the match must be in progress. An accepted result finishes the
+// match and is broadcast as MATCH_FINISHED.
+//
+// Returns the accepted result, which the caller should pass to RecordMatch, or the
+// reason the submission was refused. Both are empty if the vote was counted but
+// there is no majority yet.
+func SubmitResult(state *structs.Server, lobby *structs.Lobby, c *structs.Client, players []structs.PlayerResultArgs) (*structs.MatchResult, string) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if lobby.Host != c && !slices.Contains(lobby.Clients, c) {
+		return nil, "unauthorized"
+	}
+	if lobby.Phase != constants.PHASE_IN_PROGRESS {
+		return nil, "not in progress"
+	}
+
+	roster := Roster(lobby)
+	if reason := checkResult(lobby, roster, players); reason != "" {
+		return nil, reason
+	}
+
+	reportedBy := constants.RESULT_BY_HOST
+	if lobby.Host != c {
+		canonical := canonicalResult(players)
+		lobby.ResultVotes[c.InstanceID] = canonical
+
+		// Only count the votes of players that are still in the lobby
+		votes := 0
+		for _, player := range roster {
+			if slices.Equal(lobby.ResultVotes[player.InstanceID], canonical) {
+				votes++
+			}
+		}
+		if votes <= len(roster)/2 {
+			log.Debugf("Lobby %s match result has %d of %d votes", lobby.Name, votes, len(roster))
+			return nil, ""
+		}
+		reportedBy = constants.RESULT_BY_QUORUM
+	}
+
+	result := &structs.MatchResult{
+		ID:         ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String(),
+		GameID:     lobby.GameID,
+		Lobby:      lobby.Name,
+		ReportedBy: reportedBy,
+		StartedAt:  lobby.MatchStartedAt,
+		EndedAt:    time.Now(),
+		Players:    make([]*structs.PlayerResult, 0, len(players)),
+	}
+	for _, player := range players {
+		peer := Get(roster, player.Peer)
+		result.Players = append(result.Players, &structs.PlayerResult{
+			UserID:   peer.UserID,
+			Username: peer.Name,
+			Rank:     player.Rank,
+			Score:    player.Score,
+			Team:     teamOf(lobby, player.Peer),
+		})
+	}
+
+	setPhase(state, lobby, constants.PHASE_FINISHED)
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "MATCH_FINISHED", Payload: result})
+	return result, ""
+}
+
+// RecordMatch stores an accepted match result in the database, if there is one, and
+// hands it to the server's match result hook.
+func RecordMatch(state *structs.Server, result *structs.MatchResult) {
+	if !state.BypassDB && state.DB != nil {
+		if err := state.DB.Create(result).Error; err != nil {
+			log.Errorf("Failed to store match result %s for game %s lobby %s: %s", result.ID, result.GameID, result.Lobby, err)
+		}
+	}
+
+	state.Lock.RLock()
+	hook := state.MatchResultHook
+	state.Lock.RUnlock()
+
+	if hook != nil {
+		hook(result)
+	}
+}
+
+// checkResult checks that a match result has exactly one entry for every player
+// on the roster, and that any team it lists is the one the player is on. The caller
+// must hold the state lock.
+func checkResult(lobby *structs.Lobby, roster []*structs.Client, players []structs.PlayerResultArgs) string {
+	if len(roster) == 0 {
+		return "no players"
+	}
+
+	seen := make(map[string]bool, len(players))
+	for _, player := range players {
+		if Get(roster, player.Peer) == nil {
+			return fmt.Sprintf("value error: %s is not a player in the lobby", player.Peer)
+		}
+		if seen[player.Peer] {
+			return fmt.Sprintf("value error: %s is listed more than once", player.Peer)
+		}
+		seen[player.Peer] = true
+
+		if player.Rank < 1 {
+			return fmt.Sprintf("value error: rank of %s should be at least 1", player.Peer)
+		}
+		if player.Team != "" && FindTeam(lobby.Teams, player.Team) == nil {
+			return fmt.Sprintf("value error: team %s does not exist", player.Team)
+		}
+		if player.Team != "" && player.Team != teamOf(lobby, player.Peer) {
+			return fmt.Sprintf("value error: %s is not on team %s", player.Peer, player.Team)
+		}
+	}
+	if len(seen) != len(roster) {
+		return "value error: every player in the lobby should be listed"
+	}
+	return ""
+}
+
+// canonicalResult encodes a match result independently of the order the players are
+// listed in, so that identical results submitted by different members compare equal.
+func canonicalResult(players []structs.PlayerResultArgs) []byte {
+	sorted := slices.Clone(players)
+	slices.SortFunc(sorted, func(a, b structs.PlayerResultArgs) int {
+		return strings.Compare(a.Peer, b.Peer)
+	})
+	raw, _ := json.Marshal(sorted)
+	return raw
+}
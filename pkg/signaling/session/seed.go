@@ -0,0 +1,284 @@
+package session
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// StartSeed begins a commit-reveal round between the players of the lobby. Every
+// player commits to a secret, then reveals it, and the shared seed is the hash of
+// all the secrets. Nobody can bias the seed, since nobody knows the other secrets
+// before committing to their own.
+//
+// Returns the reason the round couldn't be started, or an empty string on success.
+func StartSeed(state *structs.Server, lobby *structs.Lobby) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if lobby.Seed != nil {
+		return "already running"
+	}
+
+	participants := make([]string, 0)
+	for _, player := range Roster(lobby) {
+		participants = append(participants, player.InstanceID)
+	}
+	if len(participants) == 0 {
+		return "no players"
+	}
+
+	startSeedRound(state, lobby, participants)
+	return ""
+}
+
+// CommitSeed records a participant's commitment, which is the SHA-256 hash of its secret.
+//
+// Returns the reason the commitment was refused, or an empty string on success.
+func CommitSeed(state *structs.Server, lobby *structs.Lobby, c *structs.Client, value string) string {
+	hash, err := hex.DecodeString(value)
+	if err != nil || len(hash) != sha256.Size {
+		return "value error: commitment should be a hex encoded SHA-256 hash"
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	round := lobby.Seed
+	if reason := checkSeedPhase(round, c, constants.SEED_PHASE_COMMIT); reason != "" {
+		return reason
+	}
+	if round.Commits[c.InstanceID] != nil {
+		return "already committed"
+	}
+
+	round.Commits[c.InstanceID] = hash
+	if len(round.Commits) == len(round.Participants) {
+		round.Timer.Stop()
+		beginReveal(state, lobby, round)
+	}
+	return ""
+}
+
+// RevealSeed checks a participant's secret against its commitment and records it.
+//
+// Returns the reason the secret was refused, or an empty string on success.
+func RevealSeed(state *structs.Server, lobby *structs.Lobby, c *structs.Client, value string) string {
+	secret, err := hex.DecodeString(value)
+	if err != nil || len(secret) < constants.SEED_SECRET_MIN || len(secret) > constants.SEED_SECRET_MAX {
+		return fmt.Sprintf("value error: secret should be between %d and %d hex encoded bytes", constants.SEED_SECRET_MIN, constants.SEED_SECRET_MAX)
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	round := lobby.Seed
+	if reason := checkSeedPhase(round, c, constants.SEED_PHASE_REVEAL); reason != "" {
+		return reason
+	}
+	if round.Reveals[c.InstanceID] != nil {
+		return "already revealed"
+	}
+
+	hash := sha256.Sum256(secret)
+	if !bytes.Equal(hash[:], round.Commits[c.InstanceID]) {
+		return "value error: secret does not match commitment"
+	}
+
+	round.Reveals[c.InstanceID] = secret
+	if len(round.Reveals) == len(round.Participants) {
+		round.Timer.Stop()
+		finishSeed(state, lobby, round)
+	}
+	return ""
+}
+
+// startSeedRound asks the given participants to commit. The caller must hold the state lock.
+func startSeedRound(state *structs.Server, lobby *structs.Lobby, participants []string) {
+	slices.Sort(participants)
+	round := &structs.SeedRound{
+		Phase:        constants.SEED_PHASE_COMMIT,
+		Participants: participants,
+		Commits:      make(map[string][]byte),
+		Reveals:      make(map[string][]byte),
+		Deadline:     time.Now().Add(constants.SEED_COMMIT_TIMEOUT),
+	}
+	round.Timer = time.AfterFunc(constants.SEED_COMMIT_TIMEOUT, func() {
+		seedTimeout(state, lobby, round, constants.SEED_PHASE_COMMIT)
+	})
+	lobby.Seed = round
+
+	log.Debugf("Lobby %s seed round started with %d participants", lobby.Name, len(participants))
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "SEED_COMMIT_REQUEST", Payload: structs.SeedRequest{
+		Phase:        round.Phase,
+		Participants: round.Participants,
+		Deadline:     round.Deadline.UnixMilli(),
+	}})
+}
+
+// beginReveal publishes the commitments and asks the participants that committed to
+// reveal their secrets. The caller must hold the state lock.
+func beginReveal(state *structs.Server, lobby *structs.Lobby, round *structs.SeedRound) {
+	committed := make([]string, 0, len(round.Commits))
+	commits := make(map[string]string, len(round.Commits))
+	for _, id := range round.Participants {
+		if hash := round.Commits[id]; hash != nil {
+			committed = append(committed, id)
+			commits[id] = hex.EncodeToString(hash)
+		}
+	}
+
+	round.Phase = constants.SEED_PHASE_REVEAL
+	round.Participants = committed
+	round.Deadline = time.Now().Add(constants.SEED_REVEAL_TIMEOUT)
+	round.Timer = time.AfterFunc(constants.SEED_REVEAL_TIMEOUT, func() {
+		seedTimeout(state, lobby, round, constants.SEED_PHASE_REVEAL)
+	})
+
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "SEED_REVEAL_REQUEST", Payload: structs.SeedRequest{
+		Phase:        round.Phase,
+		Participants: round.Participants,
+		Commits:      commits,
+		Deadline:     round.Deadline.UnixMilli(),
+	}})
+}
+
+// finishSeed combines the secrets into the seed and broadcasts it. The caller must
+// hold the state lock.
+func finishSeed(state *structs.Server, lobby *structs.Lobby, round *structs.SeedRound) {
+	lobby.Seed = nil
+
+	hash := sha256.New()
+	reveals := make(map[string]string, len(round.Reveals))
+	for _, id := range round.Participants {
+		hash.Write(round.Reveals[id])
+		reveals[id] = hex.EncodeToString(round.Reveals[id])
+	}
+
+	log.Debugf("Lobby %s seed round finished", lobby.Name)
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "SEED_RESULT", Payload: structs.SeedResult{
+		Seed:         hex.EncodeToString(hash.Sum(nil)),
+		Participants: round.Participants,
+		Reveals:      reveals,
+	}})
+}
+
+// seedTimeout ends a phase that not everyone answered in time. Players that didn't
+// commit are simply left out. Players that didn't reveal could be trying to veto a
+// seed they don't like, so they get a strike and the round starts over without them.
+// Players with too many strikes are removed from the lobby.
+//
+// The phase is the one the timer was started for, since a timer that fired just as
+// everyone answered can't be stopped anymore, and the round may have moved on.
+func seedTimeout(state *structs.Server, lobby *structs.Lobby, round *structs.SeedRound, phase string) {
+	vacated := make([]*structs.Lobby, 0)
+
+	state.Lock.Lock()
+	func() {
+		defer state.Lock.Unlock()
+
+		// The round may have finished or moved on, or the lobby closed, while we were waiting for the lock
+		if lobby.Seed != round || round.Phase != phase || state.Lobbies[lobby.GameID][lobby.Name] != lobby {
+			return
+		}
+
+		// Only players still in the lobby can take part
+		players := Roster(lobby)
+		present := func(id string) bool { return Get(players, id) != nil }
+
+		if round.Phase == constants.SEED_PHASE_COMMIT {
+			for id := range round.Commits {
+				if !present(id) {
+					delete(round.Commits, id)
+				}
+			}
+			if len(round.Commits) == 0 {
+				failSeed(lobby, "no commitments")
+				return
+			}
+			beginReveal(state, lobby, round)
+			return
+		}
+
+		removed := make([]*structs.Client, 0)
+		remaining := make([]string, 0)
+		for _, id := range round.Participants {
+			player := Get(players, id)
+			if player == nil {
+				continue
+			}
+			if round.Reveals[id] != nil {
+				remaining = append(remaining, id)
+				continue
+			}
+
+			lobby.SeedStrikes[player.InstanceID]++
+			strikes := lobby.SeedStrikes[player.InstanceID]
+			log.Debugf("Lobby %s peer %s did not reveal its seed secret (%d strikes)", lobby.Name, id, strikes)
+			message.Broadcast(Audience(lobby), structs.Packet{Opcode: "SEED_PENALTY", Payload: structs.SeedPenalty{
+				Peer:    id,
+				Strikes: strikes,
+			}})
+			if strikes >= constants.SEED_MAX_STRIKES {
+				removed = append(removed, player)
+			}
+		}
+
+		// Remove them while still holding the lock, so they can't have moved on to another lobby
+		for _, player := range removed {
+			message.Send(player, structs.Packet{Opcode: "WARNING", Payload: "You have been removed from the lobby for failing to reveal your seed secret."})
+			player.Lock.Lock()
+			if left := updateState(state, lobby, player, 0); left != nil {
+				vacated = append(vacated, left)
+			}
+			player.Lock.Unlock()
+		}
+
+		// Removing the host may have closed the lobby
+		if state.Lobbies[lobby.GameID][lobby.Name] != lobby {
+			lobby.Seed = nil
+			return
+		}
+
+		if len(remaining) == 0 {
+			failSeed(lobby, "no reveals")
+			return
+		}
+		startSeedRound(state, lobby, remaining)
+	}()
+
+	// Let waiting clients in now that the lock has been released
+	for _, left := range vacated {
+		go AdmitWaitlist(state, left)
+	}
+}
+
+// failSeed abandons the round. The caller must hold the state lock.
+func failSeed(lobby *structs.Lobby, reason string) {
+	lobby.Seed = nil
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "SEED_FAILED", Payload: reason})
+}
+
+// checkSeedPhase checks that the client takes part in the round and that the round
+// is in the given phase.
+func checkSeedPhase(round *structs.SeedRound, c *structs.Client, phase string) string {
+	if round == nil {
+		return "no round running"
+	}
+	if !slices.Contains(round.Participants, c.InstanceID) {
+		return "not participating"
+	}
+	if round.Phase != phase {
+		return "wrong phase"
+	}
+	return ""
+}
@@ -14,21 +14,60 @@ import (
 
 func DestroyLobby(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
 	if lobby != nil && c.LastState == 1 && lobby.Host == nil && len(lobby.Clients) == 0 {
-		if lobby.RelayEnabled {
-			state.Relays[c.GameID][lobby.Name].Close <- true
-			<-state.Relays[c.GameID][lobby.Name].CloseDone
-			log.Infof("Game ID %s lobby %s relay has been destroyed", c.GameID, lobby.Name)
-			delete(state.Relays[c.GameID], lobby.Name)
-		}
-		delete(state.Lobbies[c.GameID], lobby.Name)
-		log.Infof("Lobby %s has been destroyed", lobby.Name)
-		message.Broadcast(state.UninitializedPeers[c.GameID], structs.Packet{Opcode: "LOBBY_CLOSED", Payload: lobby.Name})
+		destroyLobby(state, lobby)
+	}
+}
+
+// destroyLobby removes a lobby that no longer has a host or members, along with its
+// relay. The caller must hold the state lock.
+func destroyLobby(state *structs.Server, lobby *structs.Lobby) {
+
+	// Nobody is left to answer join requests or to wait for
+	dropJoinRequests(lobby)
+	dropWaitlist(lobby, "not found")
+
+	// Spectators can't keep a lobby alive on their own
+	evict(state, lobby.Spectators)
+	lobby.Spectators = nil
+
+	if lobby.RelayEnabled {
+		state.Relays[lobby.GameID][lobby.Name].Close <- true
+		<-state.Relays[lobby.GameID][lobby.Name].CloseDone
+		log.Infof("Game ID %s lobby %s relay has been destroyed", lobby.GameID, lobby.Name)
+		delete(state.Relays[lobby.GameID], lobby.Name)
+	}
+	delete(state.Lobbies[lobby.GameID], lobby.Name)
+	log.Infof("Lobby %s has been destroyed", lobby.Name)
+	publishClosed(state, lobby)
+}
+
+// evict returns the given peers to the uninitialized state without touching the
+// lobby they were in. The caller must hold the state lock and update the lobby.
+func evict(state *structs.Server, peers []*structs.Client) {
+	for _, peer := range peers {
+		log.Debugf("Peer %s was in state %d and will become state 0\n", peer.InstanceID, peer.State)
+		peer.LastState = peer.State
+		peer.State = 0
+		peer.Lobby = ""
+		peer.Ready = false
+		peer.WatchingVars = false
+		peer.WatchedVars = nil
+		state.UninitializedPeers[peer.GameID] = And(state.UninitializedPeers[peer.GameID], peer)
+		message.Send(peer, structs.Packet{Opcode: "TRANSITION", Payload: ""})
 	}
 }
 
 func UpdateState(state *structs.Server, lobby *structs.Lobby, c *structs.Client, newstate int8, is_transitional ...bool) {
 	log.Debugf("%s %d -> %d\n", c.InstanceID, c.State, newstate)
 
+	// If the client frees up room in a lobby, let waiting clients in once the locks have been released
+	var vacated *structs.Lobby
+	defer func() {
+		if vacated != nil {
+			go AdmitWaitlist(state, vacated)
+		}
+	}()
+
 	// Add to new state with lock. Both locks MUST be acquired and released at the same time!
 	state.Lock.Lock()
 	c.Lock.Lock()
@@ -36,135 +75,207 @@ func UpdateState(state *structs.Server, lobby *structs.Lobby, c *structs.Client,
 		c.Lock.Unlock()
 		state.Lock.Unlock()
 	}(c, state)
-	func(c *structs.Client, state *structs.Server) {
+	vacated = updateState(state, lobby, c, newstate)
+}
 
-		log.Debugf("Peer %s was in state %d and is now in state %d\n", c.InstanceID, c.State, newstate)
+// updateState is the lock-free implementation of UpdateState. Returns the lobby the
+// client freed up room in, if any, so that its waitlist can be processed once the
+// locks have been released. The caller must hold the state lock and the client's lock.
+func updateState(state *structs.Server, lobby *structs.Lobby, c *structs.Client, newstate int8) (vacated *structs.Lobby) {
 
-		if lobby == nil {
-			// Try to find the lobby given the peer's lobby
-			if c.Lobby != "" {
-				lobby = state.Lobbies[c.GameID][c.Lobby]
-			}
+	log.Debugf("Peer %s was in state %d and is now in state %d\n", c.InstanceID, c.State, newstate)
+
+	if lobby == nil {
+		// Try to find the lobby given the peer's lobby
+		if c.Lobby != "" {
+			lobby = state.Lobbies[c.GameID][c.Lobby]
 		}
+	}
 
-		// First, remove from old global state
-		switch c.State {
-
-		case -1:
-			log.Warnf("WARNING: Peer", c.InstanceID, "last state was -1")
-
-		// The client is uninitialized and is either being destroyed or joining a lobby
-		case 0:
-
-			// Remove the client from the uninitialized Clients
-			state.UninitializedPeers[c.GameID] = Without(state.UninitializedPeers[c.GameID], c)
-
-		// The client was a host and the server needs to pick a new host
-		case 1:
-			if lobby != nil {
-				if len(lobby.Clients) > 0 {
-
-					// Pick the next host
-					newHost := lobby.Clients[0]
-					log.Debugf("Peer %s was in state %d and will become state 1\n", newHost.InstanceID, newHost.State)
-					newHost.State = 1
-					lobby.Host = newHost
-					lobby.Clients = Without(lobby.Clients, newHost)
-					message.Send(newHost, structs.Packet{Opcode: "TRANSITION", Payload: "host"})
-					message.Broadcast(lobby.Clients, structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
-						UserID:     newHost.UserID,
-						InstanceID: newHost.InstanceID,
-						PublicKey:  newHost.PublicKey,
-						Username:   newHost.Name,
-					}})
-
-				} else {
-					log.Debugf("Lobby %s has no members.\n", lobby.Name)
+	// First, remove from old global state
+	switch c.State {
+
+	case -1:
+		log.Warnf("WARNING: Peer", c.InstanceID, "last state was -1")
+
+	// The client is uninitialized and is either being destroyed or joining a lobby
+	case 0:
+
+		// Remove the client from the uninitialized Clients
+		state.UninitializedPeers[c.GameID] = Without(state.UninitializedPeers[c.GameID], c)
+
+		// The client no longer needs the join request or waitlist spot it may be waiting on
+		withdrawJoin(state, c)
+		leaveWaitlist(state, c)
+
+	// The client was a host and the server needs to pick a new host
+	case 1:
+		if lobby != nil {
+			if newHost := NextHost(lobby); newHost != nil {
+
+				// Pick the next host according to the lobby's migration policy
+				log.Debugf("Peer %s was in state %d and will become state 1\n", newHost.InstanceID, newHost.State)
+				newHost.State = 1
+				lobby.Host = newHost
+				lobby.Clients = Without(lobby.Clients, newHost)
+				message.Send(newHost, structs.Packet{Opcode: "TRANSITION", Payload: "host"})
+				message.Broadcast(slices.Concat(lobby.Clients, lobby.Spectators), structs.Packet{Opcode: "NEW_HOST", Payload: structs.NewPeer{
+					UserID:     newHost.UserID,
+					InstanceID: newHost.InstanceID,
+					PublicKey:  newHost.PublicKey,
+					Username:   newHost.Name,
+				}})
+
+				// Hand any pending join requests over to the new host
+				for _, request := range lobby.JoinRequests {
+					message.Send(newHost, structs.Packet{Opcode: "JOIN_REQUEST", Payload: describeRequest(request.Client, request.Spectate, request.Party)})
 				}
 
-				if lobby.Host == c && (newstate == -1 || newstate == 0) {
-					log.Debugf("Lobby %s host has been cleared since %s was the host\n", lobby.Name, c.InstanceID)
-					lobby.Host = nil
-				}
-			}
+			} else if len(lobby.Clients) > 0 {
 
-		// The client was a member and needs to be removed
-		case 2:
-			if lobby != nil {
+				// The lobby closes when the host leaves
+				log.Debugf("Lobby %s is closing since its host has left\n", lobby.Name)
+				evict(state, lobby.Clients)
+				lobby.Clients = nil
 
-				// Remove the client from the lobby Clients
-				lobby.Clients = Without(lobby.Clients, c)
+			} else {
+				log.Debugf("Lobby %s has no members.\n", lobby.Name)
+			}
+
+			if lobby.Host == c && (newstate == -1 || newstate == 0) {
+				log.Debugf("Lobby %s host has been cleared since %s was the host\n", lobby.Name, c.InstanceID)
+				lobby.Host = nil
 			}
 		}
 
-		// Then, update the client's state
-		c.LastState = c.State
+	// The client was a member and needs to be removed
+	case 2:
+		if lobby != nil {
 
-		if lobby == nil {
-			c.Lobby = ""
-		} else {
-			c.Lobby = lobby.Name
+			// Remove the client from the lobby Clients
+			lobby.Clients = Without(lobby.Clients, c)
 		}
 
-		c.State = newstate
+	// The client was a spectator and needs to be removed
+	case 3:
+		if lobby != nil {
+			lobby.Spectators = Without(lobby.Spectators, c)
+		}
+	}
 
-		// Finally, add to new global state
-		switch c.State {
+	// Free up the client's team slot if it is leaving the lobby
+	if lobby != nil && (newstate == -1 || newstate == 0) && unassign(lobby, c) {
+		BroadcastRoster(lobby)
+	}
 
-		// Intended to finalize the destruction of the client
-		case -1:
-			// Remove the client from the uninitialized Clients
-			state.UninitializedPeers[c.GameID] = Without(state.UninitializedPeers[c.GameID], c)
+	// Its connections to other peers and to the relay no longer matter either
+	if lobby != nil && (newstate == -1 || newstate == 0) {
+		forgetConnections(lobby, c)
+		leaveRelay(state, lobby, c)
+		c.WatchingVars = false
+		c.WatchedVars = nil
+	}
 
-			// Notify members the client is leaving
-			if lobby != nil {
+	// Remember the lobby so its waitlist can be processed
+	if lobby != nil && (newstate == -1 || newstate == 0) && c.State > 0 {
+		vacated = lobby
+	}
 
-				// Does nothing if there are no peers
-				message.Broadcast(Without(And(lobby.Clients, lobby.Host), c), structs.Packet{Opcode: "PEER_LEFT", Payload: c.InstanceID})
+	// A player leaving the lobby is no longer ready and stops any pending match start
+	if lobby != nil && (newstate == -1 || newstate == 0) && (c.State == 1 || c.State == 2) {
+		c.Ready = false
+		cancelCountdown(state, lobby)
+	}
 
-				// Does nothing if the lobby state isn't ready to be deleted
-				if c.LastState == 1 {
-					DestroyLobby(state, lobby, c)
-				}
-			}
+	// Then, update the client's state
+	c.LastState = c.State
+
+	if lobby == nil {
+		c.Lobby = ""
+	} else {
+		c.Lobby = lobby.Name
+	}
+
+	c.State = newstate
+
+	// Finally, add to new global state
+	switch c.State {
+
+	// Intended to finalize the destruction of the client
+	case -1:
+		// Remove the client from the uninitialized Clients
+		state.UninitializedPeers[c.GameID] = Without(state.UninitializedPeers[c.GameID], c)
+		unsubscribe(state, c)
+		leaveParty(state, c)
+		dropPartyInvites(state, c)
+
+		// Lobbies can't outlive their dedicated host
+		if c.Dedicated {
+			releaseLobbies(state, c)
+		}
 
-		// Client is now uninitialized
-		case 0:
-			state.UninitializedPeers[c.GameID] = And(state.UninitializedPeers[c.GameID], c)
-			c.Lobby = ""
-			message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: ""})
+		// Notify members the client is leaving
+		if lobby != nil {
 
+			// Does nothing if there are no peers
+			message.Broadcast(Without(Audience(lobby), c), structs.Packet{Opcode: "PEER_LEFT", Payload: c.InstanceID})
+
+			// Does nothing if the lobby state isn't ready to be deleted
 			if c.LastState == 1 {
 				DestroyLobby(state, lobby, c)
 			}
+		}
 
-		// Client needs to become a host
-		case 1:
+	// Client is now uninitialized
+	case 0:
+		state.UninitializedPeers[c.GameID] = And(state.UninitializedPeers[c.GameID], c)
+		c.Lobby = ""
+		message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: ""})
 
-			// Get the old host
-			oldHost := lobby.Host
+		if c.LastState == 1 {
+			DestroyLobby(state, lobby, c)
+		}
 
-			// Move the old host to the lobby Clients
-			if oldHost != nil {
-				log.Debugf("Peer %s was in state %d and will become state 2\n", oldHost.InstanceID, oldHost.State)
-				oldHost.State = 2
-				lobby.Clients = And(lobby.Clients, oldHost)
-				message.Send(oldHost, structs.Packet{Opcode: "TRANSITION", Payload: "peer"})
-			}
+	// Client needs to become a host
+	case 1:
 
-			// Set the new host
-			lobby.Host = c
-			message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "host"})
+		// Get the old host
+		oldHost := lobby.Host
 
-		// Client needs to become a member
-		case 2:
-			lobby.Clients = And(lobby.Clients, c)
-			message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "peer"})
+		// Move the old host to the lobby Clients
+		if oldHost != nil {
+			log.Debugf("Peer %s was in state %d and will become state 2\n", oldHost.InstanceID, oldHost.State)
+			oldHost.State = 2
+			lobby.Clients = And(lobby.Clients, oldHost)
+			message.Send(oldHost, structs.Packet{Opcode: "TRANSITION", Payload: "peer"})
 		}
 
-		// Perform cleanup duties
-		TriggerCleanup(state, lobby, c)
-	}(c, state)
+		// Set the new host
+		lobby.Host = c
+		message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "host"})
+
+	// Client needs to become a member
+	case 2:
+		lobby.Clients = And(lobby.Clients, c)
+		delete(lobby.Reservations, c.UserID)
+		touch(lobby)
+		message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "peer"})
+
+	// Client needs to become a spectator
+	case 3:
+		lobby.Spectators = And(lobby.Spectators, c)
+		touch(lobby)
+		message.Send(c, structs.Packet{Opcode: "TRANSITION", Payload: "spectator"})
+	}
+
+	// Tell subscribers about the lobby's new player count and host
+	if lobby != nil && state.Lobbies[lobby.GameID][lobby.Name] == lobby {
+		publishLobby(state, lobby)
+	}
+
+	// Perform cleanup duties
+	TriggerCleanup(state, lobby, c)
+	return vacated
 }
 
 func TriggerCleanup(state *structs.Server, lobby *structs.Lobby, c *structs.Client) {
@@ -175,6 +286,7 @@ func TriggerCleanup(state *structs.Server, lobby *structs.Lobby, c *structs.Clie
 		delete(state.Lobbies, c.GameID)
 		delete(state.UninitializedPeers, c.GameID)
 		delete(state.Relays, c.GameID)
+		delete(state.Subscribers, c.GameID)
 		log.Infof("Game ID %s has been destroyed", c.GameID)
 	}
 }
@@ -217,6 +329,17 @@ func CloseWithWarningMessage(c *structs.Client, message string) {
 	c.Conn.Close()
 }
 
+// Audience returns every client in the given lobby: the host, the members
+// and the spectators.
+func Audience(lobby *structs.Lobby) []*structs.Client {
+	peers := make([]*structs.Client, 0, len(lobby.Clients)+len(lobby.Spectators)+1)
+	if lobby.Host != nil {
+		peers = append(peers, lobby.Host)
+	}
+	peers = append(peers, lobby.Clients...)
+	return append(peers, lobby.Spectators...)
+}
+
 // get returns the client with the given id from the given slice of clients.
 // Returns nil if no client with the given id is found.
 func Get(peers []*structs.Client, id string) *structs.Client {
@@ -0,0 +1,218 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// NewTeams validates the given team definitions and creates empty teams from them.
+//
+// Returns the reason the definitions were rejected, or an empty string if they are valid.
+func NewTeams(args []structs.TeamArgs) ([]*structs.Team, string) {
+	if len(args) > constants.MAX_TEAMS {
+		return nil, fmt.Sprintf("too many teams (maximum is %d)", constants.MAX_TEAMS)
+	}
+
+	teams := make([]*structs.Team, 0, len(args))
+	for _, arg := range args {
+		if arg.Name == "" {
+			return nil, "team names cannot be empty"
+		}
+		if FindTeam(teams, arg.Name) != nil {
+			return nil, fmt.Sprintf("team %s is defined more than once", arg.Name)
+		}
+		if arg.Capacity < 1 || arg.Capacity > constants.MAX_TEAM_SIZE {
+			return nil, fmt.Sprintf("team %s capacity should be between 1 and %d", arg.Name, constants.MAX_TEAM_SIZE)
+		}
+		teams = append(teams, &structs.Team{
+			Name:     arg.Name,
+			Capacity: arg.Capacity,
+			Slots:    make([]string, arg.Capacity),
+		})
+	}
+	return teams, ""
+}
+
+// FindTeam returns the team with the given name, or nil if it does not exist.
+func FindTeam(teams []*structs.Team, name string) *structs.Team {
+	for _, team := range teams {
+		if team.Name == name {
+			return team
+		}
+	}
+	return nil
+}
+
+// SetTeams replaces the teams of a lobby. Peers keep their slot if their team and
+// slot still exist, and are otherwise moved to the first team that has room.
+func SetTeams(state *structs.Server, lobby *structs.Lobby, args []structs.TeamArgs) string {
+	teams, reason := NewTeams(args)
+	if reason != "" {
+		return reason
+	}
+
+	state.Lock.Lock()
+	func() {
+		defer state.Lock.Unlock()
+
+		// Carry over the existing assignments where possible
+		displaced := make([]*structs.Client, 0)
+		for _, old := range lobby.Teams {
+			for slot, id := range old.Slots {
+				if id == "" {
+					continue
+				}
+				if team := FindTeam(teams, old.Name); team != nil && int64(slot) < team.Capacity {
+					team.Slots[slot] = id
+				} else if peer := Get(Audience(lobby), id); peer != nil {
+					displaced = append(displaced, peer)
+				}
+			}
+		}
+		lobby.Teams = teams
+
+		for _, peer := range displaced {
+			autoAssign(lobby, peer)
+		}
+	}()
+
+	BroadcastRoster(lobby)
+	return ""
+}
+
+// AssignSlot moves the given peer into a team. If slot is nil, the first free slot
+// in the team is used.
+//
+// Returns the reason the assignment was refused, or an empty string on success.
+func AssignSlot(state *structs.Server, lobby *structs.Lobby, c *structs.Client, name string, slot *int64) string {
+	state.Lock.Lock()
+	reason := func() string {
+		defer state.Lock.Unlock()
+
+		team := FindTeam(lobby.Teams, name)
+		if team == nil {
+			return "no team found"
+		}
+
+		target := int64(-1)
+		if slot != nil {
+			if *slot < 0 || *slot >= team.Capacity {
+				return "no slot found"
+			}
+			if team.Slots[*slot] != "" && team.Slots[*slot] != c.InstanceID {
+				return "slot taken"
+			}
+			target = *slot
+		} else {
+			for i, id := range team.Slots {
+				if id == "" || id == c.InstanceID {
+					target = int64(i)
+					break
+				}
+			}
+			if target == -1 {
+				return "team full"
+			}
+		}
+
+		unassign(lobby, c)
+		team.Slots[target] = c.InstanceID
+		return ""
+	}()
+
+	if reason == "" {
+		BroadcastRoster(lobby)
+	}
+	return reason
+}
+
+// AutoAssign places a peer that isn't on a team yet into the team with the most free
+// slots. The peer is left unassigned if every team is full.
+//
+// Returns true if the peer was assigned and the new roster has been broadcast.
+func AutoAssign(state *structs.Server, lobby *structs.Lobby, c *structs.Client) bool {
+	if len(lobby.Teams) == 0 {
+		return false
+	}
+
+	state.Lock.Lock()
+	assigned := func() bool {
+		defer state.Lock.Unlock()
+		return autoAssign(lobby, c)
+	}()
+
+	if assigned {
+		BroadcastRoster(lobby)
+	}
+	return assigned
+}
+
+// BroadcastRoster sends the authoritative team roster to everyone in the lobby.
+func BroadcastRoster(lobby *structs.Lobby) {
+	if len(lobby.Teams) == 0 {
+		return
+	}
+	message.Broadcast(Audience(lobby), structs.Packet{Opcode: "TEAM_ROSTER", Payload: lobby.Teams})
+}
+
+// autoAssign is the lock-free implementation of AutoAssign.
+func autoAssign(lobby *structs.Lobby, c *structs.Client) bool {
+	var best *structs.Team
+	var bestFree int
+	for _, team := range lobby.Teams {
+		free := 0
+		for _, id := range team.Slots {
+			if id == c.InstanceID {
+				return false
+			}
+			if id == "" {
+				free++
+			}
+		}
+		if free > bestFree {
+			best, bestFree = team, free
+		}
+	}
+
+	if best == nil {
+		return false
+	}
+
+	for i, id := range best.Slots {
+		if id == "" {
+			best.Slots[i] = c.InstanceID
+			break
+		}
+	}
+	return true
+}
+
+// unassign removes a peer from whichever team slot it holds.
+// Returns true if the peer was on a team.
+func unassign(lobby *structs.Lobby, c *structs.Client) bool {
+	for _, team := range lobby.Teams {
+		for i, id := range team.Slots {
+			if id == c.InstanceID {
+				team.Slots[i] = ""
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// teamOf returns the name of the team the peer holds a slot on, or an empty string
+// if it isn't on a team.
+func teamOf(lobby *structs.Lobby, id string) string {
+	for _, team := range lobby.Teams {
+		for _, slot := range team.Slots {
+			if slot == id {
+				return team.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/cloudlink-omega/signaling/pkg/constants"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// ValidVarPermission returns true if the given variable permission is known.
+// An empty permission is valid and means anyone.
+func ValidVarPermission(permission string) bool {
+	switch permission {
+	case "",
+		constants.VAR_ANYONE,
+		constants.VAR_HOST_ONLY,
+		constants.VAR_OWNER_ONLY:
+		return true
+	}
+	return false
+}
+
+// GetVariable returns the current state of a lobby variable.
+// Returns false if the variable does not exist.
+func GetVariable(state *structs.Server, lobby *structs.Lobby, key string) (structs.VarEvent, bool) {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+
+	variable := lobby.Variables[key]
+	if variable == nil {
+		return structs.VarEvent{}, false
+	}
+	return describeVar(key, variable), true
+}
+
+// SetVariable creates or changes a lobby variable and tells the lobby about it.
+//
+// Returns the reason the change was refused, or an empty string on success.
+func SetVariable(state *structs.Server, lobby *structs.Lobby, c *structs.Client, args structs.VarArgs) string {
+	if args.Key == "" || len(args.Key) > constants.MAX_VARIABLE_KEY {
+		return fmt.Sprintf("value error: key should be between 1 and %d characters", constants.MAX_VARIABLE_KEY)
+	}
+	if !ValidVarPermission(args.Permission) {
+		return "value error: unknown permission"
+	}
+	if raw, err := json.Marshal(args.Value); err != nil || len(raw) > constants.MAX_VARIABLE_SIZE {
+		return fmt.Sprintf("value error: value should be at most %d bytes", constants.MAX_VARIABLE_SIZE)
+	}
+
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	variable := lobby.Variables[args.Key]
+	if reason := checkVarWrite(lobby, c, variable, args.Version); reason != "" {
+		return reason
+	}
+
+	if variable == nil {
+		if len(lobby.Variables) >= constants.MAX_LOBBY_VARIABLES {
+			return fmt.Sprintf("too many variables (maximum is %d)", constants.MAX_LOBBY_VARIABLES)
+		}
+		permission := args.Permission
+		if permission == "" {
+			permission = constants.VAR_ANYONE
+		}
+
+		// Owner-only variables need an owner that other clients can't also claim to be
+		if permission == constants.VAR_OWNER_ONLY && c.UserID == "" {
+			return "unauthorized"
+		}
+		variable = &structs.Variable{Owner: c.UserID, Permission: permission}
+		lobby.Variables[args.Key] = variable
+
+	} else if args.Permission != "" && args.Permission != variable.Permission {
+
+		// Only the host may change who else can write to an existing variable
+		if lobby.Host != c {
+			return "unauthorized"
+		}
+		variable.Permission = args.Permission
+	}
+
+	lobby.VarClock++
+	variable.Value = args.Value
+	variable.Version = lobby.VarClock
+
+	message.Broadcast(varAudience(lobby, args.Key), structs.Packet{Opcode: "VAR_CHANGED", Payload: describeVar(args.Key, variable)})
+	return ""
+}
+
+// DeleteVariable removes a lobby variable and tells the lobby about it. The host
+// may delete any variable.
+//
+// Returns the reason the deletion was refused, or an empty string on success.
+func DeleteVariable(state *structs.Server, lobby *structs.Lobby, c *structs.Client, args structs.VarArgs) string {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	variable := lobby.Variables[args.Key]
+	if variable == nil {
+		return "not found"
+	}
+	if lobby.Host != c {
+		if reason := checkVarWrite(lobby, c, variable, args.Version); reason != "" {
+			return reason
+		}
+	} else if args.Version != nil && *args.Version != variable.Version {
+		return "conflict"
+	}
+
+	delete(lobby.Variables, args.Key)
+	lobby.VarClock++
+
+	message.Broadcast(varAudience(lobby, args.Key), structs.Packet{Opcode: "VAR_CHANGED", Payload: structs.VarEvent{
+		Key:     args.Key,
+		Version: lobby.VarClock,
+		Deleted: true,
+	}})
+	return ""
+}
+
+// WatchVariables returns the current state of the given lobby variables (or of all of
+// them if no keys are given). Spectators are also told about later changes, which
+// players always are. From then on, the client is only told about changes to the
+// given keys.
+func WatchVariables(state *structs.Server, lobby *structs.Lobby, c *structs.Client, keys []string) []structs.VarEvent {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	c.WatchingVars = true
+	c.WatchedVars = keys
+
+	snapshot := make([]structs.VarEvent, 0, len(lobby.Variables))
+	for key, variable := range lobby.Variables {
+		if len(keys) == 0 || slices.Contains(keys, key) {
+			snapshot = append(snapshot, describeVar(key, variable))
+		}
+	}
+	return snapshot
+}
+
+// checkVarWrite checks whether the client may change a variable, which is nil if it
+// doesn't exist yet. If version is set, the variable must be at that version.
+func checkVarWrite(lobby *structs.Lobby, c *structs.Client, variable *structs.Variable, version *uint64) string {
+
+	// Spectators can only watch
+	if lobby.Host != c && !slices.Contains(lobby.Clients, c) {
+		return "unauthorized"
+	}
+
+	if variable != nil {
+		switch variable.Permission {
+		case constants.VAR_HOST_ONLY:
+			if lobby.Host != c {
+				return "unauthorized"
+			}
+		case constants.VAR_OWNER_ONLY:
+			if variable.Owner == "" || variable.Owner != c.UserID {
+				return "unauthorized"
+			}
+		}
+	}
+
+	// Compare and set
+	if version != nil {
+		current := uint64(0)
+		if variable != nil {
+			current = variable.Version
+		}
+		if *version != current {
+			return "conflict"
+		}
+	}
+	return ""
+}
+
+// varAudience returns the peers that are told about changes to the given variable:
+// the host, the members and any spectators that are watching, except for those
+// that only watch other keys.
+func varAudience(lobby *structs.Lobby, key string) []*structs.Client {
+	peers := make([]*structs.Client, 0)
+	for _, peer := range Audience(lobby) {
+		if slices.Contains(lobby.Spectators, peer) && !peer.WatchingVars {
+			continue
+		}
+		if len(peer.WatchedVars) > 0 && !slices.Contains(peer.WatchedVars, key) {
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func describeVar(key string, variable *structs.Variable) structs.VarEvent {
+	return structs.VarEvent{
+		Key:        key,
+		Value:      variable.Value,
+		Version:    variable.Version,
+		Owner:      variable.Owner,
+		Permission: variable.Permission,
+	}
+}
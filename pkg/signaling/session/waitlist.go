@@ -0,0 +1,161 @@
+package session
+
+import (
+	"slices"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// Enqueue puts the client on the lobby's waitlist and tells it its position.
+func Enqueue(state *structs.Server, lobby *structs.Lobby, c *structs.Client, spectate bool, invited bool) {
+	state.Lock.Lock()
+	position := func() int {
+		defer state.Lock.Unlock()
+
+		if lobby.Closing {
+			return 0
+		}
+
+		// A client can only wait for one lobby at a time
+		leaveWaitlist(state, c)
+
+		lobby.Waitlist = append(lobby.Waitlist, &structs.JoinRequest{Client: c, Spectate: spectate, Invited: invited})
+		c.Waitlisted = lobby.Name
+		return len(lobby.Waitlist)
+	}()
+
+	if position == 0 {
+		message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "not found"})
+		return
+	}
+	message.Send(c, structs.Packet{Opcode: "JOIN_ACK", Payload: "waitlisted"})
+	message.Send(c, structs.Packet{Opcode: "WAITLIST_POSITION", Payload: position})
+}
+
+// AdmitWaitlist lets waiting clients into the lobby, in order, for as long as there
+// is room for them. A client that requires host approval is sent to the host instead.
+// Everyone still waiting is told their new position.
+func AdmitWaitlist(state *structs.Server, lobby *structs.Lobby) {
+	lobby.Lock.Lock()
+	defer lobby.Lock.Unlock()
+
+	// Nobody is let into a lobby that is closing or has been destroyed
+	if !admitting(state, lobby) {
+		return
+	}
+
+	for {
+		state.Lock.Lock()
+		next, position := func() (*structs.JoinRequest, int) {
+			defer state.Lock.Unlock()
+
+			// Nothing to do if the lobby has been destroyed
+			if state.Lobbies[lobby.GameID][lobby.Name] != lobby || lobby.Closing {
+				return nil, 0
+			}
+
+			for i, entry := range lobby.Waitlist {
+				if Joinable(lobby, entry.Spectate) == "" && Vacancy(lobby, entry.Spectate, []*structs.Client{entry.Client}) == "" {
+					lobby.Waitlist = append(lobby.Waitlist[:i:i], lobby.Waitlist[i+1:]...)
+					entry.Client.Waitlisted = ""
+					return entry, i
+				}
+			}
+			return nil, 0
+		}()
+
+		if next == nil {
+			break
+		}
+
+		// Approval doesn't hold the slot, so only ask the host about one client at a time
+		if lobby.RequireApproval && !next.Invited {
+			RequestJoin(state, lobby, next.Client, next.Spectate, nil)
+			break
+		}
+
+		// A client that still can't get in keeps its place if it may wait, and is told why otherwise
+		if reason := join(state, lobby, []*structs.Client{next.Client}, next.Spectate); reason != "" {
+			if !requeue(state, lobby, next, position) {
+				message.Send(next.Client, structs.Packet{Opcode: "JOIN_ACK", Payload: reason})
+			}
+			break
+		}
+	}
+
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+	sendPositions(lobby)
+}
+
+// requeue puts a client that couldn't be admitted back in its place on the waitlist.
+// Returns false if the lobby no longer lets clients in, or the client has since
+// joined a lobby or started waiting somewhere else.
+func requeue(state *structs.Server, lobby *structs.Lobby, entry *structs.JoinRequest, position int) bool {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+
+	if state.Lobbies[lobby.GameID][lobby.Name] != lobby || lobby.Closing {
+		return false
+	}
+	if entry.Client.State != 0 || entry.Client.Waitlisted != "" {
+		return false
+	}
+
+	position = min(position, len(lobby.Waitlist))
+	lobby.Waitlist = slices.Insert(lobby.Waitlist, position, entry)
+	entry.Client.Waitlisted = lobby.Name
+	return true
+}
+
+// admitting returns true if the lobby still lets clients in.
+func admitting(state *structs.Server, lobby *structs.Lobby) bool {
+	state.Lock.RLock()
+	defer state.Lock.RUnlock()
+	return state.Lobbies[lobby.GameID][lobby.Name] == lobby && !lobby.Closing
+}
+
+// leaveWaitlist removes the client from the waitlist it is on, if any, and updates
+// the positions of everyone behind it. The caller must hold the state lock.
+func leaveWaitlist(state *structs.Server, c *structs.Client) {
+	if c.Waitlisted == "" {
+		return
+	}
+
+	if lobby := state.Lobbies[c.GameID][c.Waitlisted]; lobby != nil {
+		for i, entry := range lobby.Waitlist {
+			if entry.Client == c {
+				lobby.Waitlist = append(lobby.Waitlist[:i:i], lobby.Waitlist[i+1:]...)
+				sendPositions(lobby)
+				break
+			}
+		}
+	}
+	c.Waitlisted = ""
+}
+
+// ClearWaitlist turns away everyone waiting for the lobby, for when the host
+// disables the waitlist.
+func ClearWaitlist(state *structs.Server, lobby *structs.Lobby) {
+	state.Lock.Lock()
+	defer state.Lock.Unlock()
+	dropWaitlist(lobby, "full")
+}
+
+// dropWaitlist empties the waitlist and sends each waiting client the given
+// JOIN_ACK reason. The caller must hold the state lock.
+func dropWaitlist(lobby *structs.Lobby, reason string) {
+	for _, entry := range lobby.Waitlist {
+		entry.Client.Waitlisted = ""
+		message.Send(entry.Client, structs.Packet{Opcode: "JOIN_ACK", Payload: reason})
+	}
+	lobby.Waitlist = nil
+}
+
+// sendPositions tells every waiting client its current (one-based) position.
+func sendPositions(lobby *structs.Lobby) {
+	for i, entry := range lobby.Waitlist {
+		message.Send(entry.Client, structs.Packet{Opcode: "WAITLIST_POSITION", Payload: i + 1})
+	}
+}
@@ -2,6 +2,7 @@ package signaling
 
 import (
 	"crypto/rand"
+	"net"
 	"slices"
 
 	"sync"
@@ -12,7 +13,9 @@ import (
 	"github.com/cloudlink-omega/accounts/pkg/authorization"
 	account_structs "github.com/cloudlink-omega/accounts/pkg/structs"
 	backend "github.com/cloudlink-omega/backend/pkg/database"
+	"github.com/cloudlink-omega/signaling/pkg/constants"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/handlers"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/latency"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/message"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/origin"
 	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
@@ -37,11 +40,20 @@ func Initialize(allowedorigins []string, turnonly bool, auth *authorization.Auth
 		Lobbies:                  make(map[string]map[string]*structs.Lobby),
 		GlobalPeerIDs:            make(map[string][]string),
 		UninitializedPeers:       make(map[string][]*structs.Client),
+		Subscribers:              make(map[string][]*structs.Client),
+		Parties:                  make(map[string]map[string]*structs.Party),
+		ChatHistory:              make(map[string]map[string][]time.Time),
 		Authorization:            auth,
 		DB:                       db,
 		GamesDB:                  gamedb,
 		BypassDB:                 bypass_db,
+		PingInterval:             constants.PING_INTERVAL,
+		ReadTimeout:              constants.PING_INTERVAL * time.Duration(constants.HEARTBEAT_MISSES),
+		GameSettings:             make(map[string]*structs.GameSettings),
+		MaxRelays:                constants.MAX_RELAYS,
+		RelaySecret:              make([]byte, 32),
 	}
+	rand.Read(s.RelaySecret)
 
 	if bypass_db {
 		log.Info("Signaling server is running in authless mode.")
@@ -57,16 +69,48 @@ func Initialize(allowedorigins []string, turnonly bool, auth *authorization.Auth
 				&types.User{},
 				&types.Developer{},
 				&types.DeveloperGame{},
+				&structs.MatchResult{},
+				&structs.PlayerResult{},
 			)
 		}
 	}
 
+	go session.RunJanitor((*structs.Server)(s), constants.JANITOR_INTERVAL)
+
 	return s
 }
 
+// SetGameSettings configures optional behaviour, such as lobby expiry, for a game.
+// Passing nil restores the defaults.
+func (s *Server) SetGameSettings(gameID string, settings *structs.GameSettings) {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	if settings == nil {
+		delete(s.GameSettings, gameID)
+		return
+	}
+	s.GameSettings[gameID] = settings
+}
+
+// SetMatchResultHook registers a function that is called with every recorded match
+// result, for example to feed rankings. Passing nil removes the hook.
+func (s *Server) SetMatchResultHook(hook func(*structs.MatchResult)) {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+	s.MatchResultHook = hook
+}
+
 func RunClient(state *Server, c *structs.Client) {
 	for {
+		if state.ReadTimeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(state.ReadTimeout))
+		}
 		clientMsg, err := message.Read(c)
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			log.Infof("Client %s missed too many heartbeats and will be disconnected", c.InstanceID)
+			return
+		}
 		if err != nil {
 			log.Errorf("Client %s read error: %s", c.InstanceID, err.Error())
 			return
@@ -76,6 +120,7 @@ func RunClient(state *Server, c *structs.Client) {
 }
 
 func CloseClient(state *Server, c *structs.Client) {
+	close(c.Done)
 	session.UpdateState((*structs.Server)(state), nil, c, -1)
 	if game := state.GlobalPeerIDs[c.GameID]; game != nil {
 		state.GlobalPeerIDs[c.GameID] = slices.Delete(state.GlobalPeerIDs[c.GameID], slices.Index(game, c.InstanceID), 1)
@@ -182,6 +227,8 @@ func (s *Server) Handler(Conn *websocket.Conn) {
 		TokenWasPresent: Conn.Query("token") != "",
 		Lock:            &sync.Mutex{},
 		State:           0,
+		ConnectedAt:     time.Now(),
+		Done:            make(chan bool),
 		TransmitLock:    &sync.Mutex{},
 		GameID:          Conn.Query("ugi"),
 	}
@@ -208,6 +255,7 @@ func (s *Server) Handler(Conn *websocket.Conn) {
 		}
 		client.AuthedWithCookie = true
 		client.InstanceID = claims.ULID + "_" + Conn.Query("ugi")
+		client.UserID = claims.ULID
 		client.Name = claims.Username
 		if slices.Contains(s.GlobalPeerIDs[Conn.Query("ugi")], client.InstanceID) {
 			log.Infof("Game ID %s client with ID %s already exists", Conn.Query("ugi"), client.InstanceID)
@@ -218,24 +266,40 @@ func (s *Server) Handler(Conn *websocket.Conn) {
 
 	RegisterClient(s, client)
 	defer CloseClient(s, client)
+	latency.Track(client, s.PingInterval, s.ReadTimeout)
 	RunClient(s, client)
 }
 
 func HandleMessage(state *Server, c *structs.Client, wsMsg structs.Packet) {
+
+	// Anything other than a keepalive or clock sync counts as lobby activity
+	if wsMsg.Opcode != "KEEPALIVE" && wsMsg.Opcode != "TIME_SYNC" && c.Lobby != "" {
+		session.Touch((*structs.Server)(state), c)
+	}
+
 	switch wsMsg.Opcode {
 
 	case "KEEPALIVE":
-		handlers.Keepalive((*structs.Server)(state), c)
+		handlers.Keepalive((*structs.Server)(state), c, wsMsg)
+
+	case "TIME_SYNC":
+		handlers.Time_Sync((*structs.Server)(state), c, wsMsg)
 
 	case "INIT":
 		handlers.Init((*structs.Server)(state), c, wsMsg)
 
 	case "LIST_LOBBIES":
-		handlers.List_Lobbies((*structs.Server)(state), c)
+		handlers.List_Lobbies((*structs.Server)(state), c, wsMsg)
 
 	case "FIND_LOBBY":
 		handlers.Find_Lobby((*structs.Server)(state), c, wsMsg)
 
+	case "SUBSCRIBE_LOBBIES":
+		handlers.Subscribe_Lobbies((*structs.Server)(state), c, wsMsg)
+
+	case "UNSUBSCRIBE_LOBBIES":
+		handlers.Unsubscribe_Lobbies((*structs.Server)(state), c)
+
 	case "CREATE_LOBBY":
 		handlers.Create_Lobby((*structs.Server)(state), c, wsMsg)
 
@@ -245,6 +309,60 @@ func HandleMessage(state *Server, c *structs.Client, wsMsg structs.Packet) {
 	case "MANAGE_LOBBY":
 		handlers.Manage_Lobby((*structs.Server)(state), c, wsMsg)
 
+	case "SWITCH_TEAM":
+		handlers.Switch_Team((*structs.Server)(state), c, wsMsg)
+
+	case "READY":
+		handlers.Ready((*structs.Server)(state), c, wsMsg)
+
+	case "START":
+		handlers.Start((*structs.Server)(state), c, wsMsg)
+
+	case "LOBBY_CHAT":
+		handlers.Lobby_Chat((*structs.Server)(state), c, wsMsg)
+
+	case "DIRECT_CHAT":
+		handlers.Direct_Chat((*structs.Server)(state), c, wsMsg)
+
+	case "PARTY":
+		handlers.Party((*structs.Server)(state), c, wsMsg)
+
+	case "CONNECTION_REPORT":
+		handlers.Connection_Report((*structs.Server)(state), c, wsMsg)
+
+	case "RELAY_OFFER":
+		handlers.Relay_Offer((*structs.Server)(state), c, wsMsg)
+
+	case "RELAY_TICKET":
+		handlers.Relay_Ticket((*structs.Server)(state), c, wsMsg)
+
+	case "RELAY_ICE":
+		handlers.Relay_Ice((*structs.Server)(state), c, wsMsg)
+
+	case "VAR_GET":
+		handlers.Var_Get((*structs.Server)(state), c, wsMsg)
+
+	case "VAR_SET":
+		handlers.Var_Set((*structs.Server)(state), c, wsMsg)
+
+	case "VAR_DELETE":
+		handlers.Var_Delete((*structs.Server)(state), c, wsMsg)
+
+	case "VAR_WATCH":
+		handlers.Var_Watch((*structs.Server)(state), c, wsMsg)
+
+	case "SEED_START":
+		handlers.Seed_Start((*structs.Server)(state), c, wsMsg)
+
+	case "SEED_COMMIT":
+		handlers.Seed_Commit((*structs.Server)(state), c, wsMsg)
+
+	case "SEED_REVEAL":
+		handlers.Seed_Reveal((*structs.Server)(state), c, wsMsg)
+
+	case "MATCH_RESULT":
+		handlers.Match_Result((*structs.Server)(state), c, wsMsg)
+
 	default:
 		message.Send(c, structs.Packet{Opcode: "WARNING", Payload: "unknown or unimplemented opcode"})
 	}
@@ -0,0 +1,54 @@
+package signaling
+
+import (
+	"time"
+
+	"github.com/cloudlink-omega/signaling/pkg/signaling/relay"
+	"github.com/cloudlink-omega/signaling/pkg/signaling/session"
+	"github.com/cloudlink-omega/signaling/pkg/structs"
+)
+
+// RelayStats returns the peer count and bandwidth counters of every relay of the
+// given game, for use by administrative tooling.
+func (s *Server) RelayStats(gameID string) []structs.RelayStats {
+	s.Lock.RLock()
+	relays := make([]*structs.Relay, 0, len(s.Relays[gameID]))
+	for _, r := range s.Relays[gameID] {
+		relays = append(relays, r)
+	}
+	s.Lock.RUnlock()
+
+	stats := make([]structs.RelayStats, 0, len(relays))
+	for _, r := range relays {
+		stats = append(stats, relay.Stats(r))
+	}
+	return stats
+}
+
+// ClientStats returns connection statistics for every client connected to the given game,
+// for use by administrative tooling.
+func (s *Server) ClientStats(gameID string) []structs.ClientStats {
+	s.Lock.RLock()
+	defer s.Lock.RUnlock()
+
+	clients := make([]*structs.Client, 0)
+	clients = append(clients, s.UninitializedPeers[gameID]...)
+	for _, lobby := range s.Lobbies[gameID] {
+		clients = append(clients, session.Audience(lobby)...)
+	}
+
+	stats := make([]structs.ClientStats, 0, len(clients))
+	for _, c := range clients {
+		stats = append(stats, structs.ClientStats{
+			InstanceID: c.InstanceID,
+			UserID:     c.UserID,
+			Username:   c.Name,
+			State:      c.State,
+			Lobby:      c.Lobby,
+			RTT:        time.Duration(c.RTT.Load()).Milliseconds(),
+			Jitter:     time.Duration(c.Jitter.Load()).Milliseconds(),
+			Reported:   time.Duration(c.ReportedLatency.Load()).Milliseconds(),
+		})
+	}
+	return stats
+}
@@ -2,6 +2,8 @@ package structs
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cloudlink-omega/storage/pkg/types"
 	"github.com/gofiber/contrib/websocket"
@@ -17,11 +19,25 @@ type Client struct {
 	Token            string
 	TokenWasPresent  bool
 	Valid            bool
-	State            int8 // -1 - destroyed, 0 - uninitialized, 1 - host, 2 - member
+	State            int8 // -1 - destroyed, 0 - uninitialized, 1 - host, 2 - member, 3 - spectator
 	LastState        int8
 	Lobby            string
 	PublicKey        string
 	Name             string
 	GameID           string
 	Game             *types.DeveloperGame
+	Ready            bool
+	ConnectedAt      time.Time
+	ReportedLatency  atomic.Int64    // Latency the client last reported in a KEEPALIVE, in nanoseconds (zero if unknown)
+	RTT              atomic.Int64    // Smoothed round-trip time measured by the server, in nanoseconds (zero if not yet measured)
+	Jitter           atomic.Int64    // Smoothed round-trip time variation measured by the server, in nanoseconds
+	Done             chan bool       // Closed once the client has disconnected
+	PendingJoin      string          // Name of the lobby the client is waiting to be approved for
+	Waitlisted       string          // Name of the lobby whose waitlist the client is on
+	Subscription     *LobbyQuery     // Lobby list subscription, nil if not subscribed
+	VisibleLobbies   map[string]bool // Lobbies the subscriber has been told about
+	Party            string          // ID of the party the client belongs to, empty if not in a party
+	Dedicated        bool            // Whether the client is a dedicated (headless) host, which stays uninitialized and may host several lobbies
+	WatchingVars     bool            // Whether a spectator has asked to be told about lobby variable changes
+	WatchedVars      []string        // Variable keys the client asked to be told about, empty for all of them
 }
@@ -2,17 +2,76 @@ package structs
 
 import (
 	"sync"
+	"time"
 )
 
 type Lobby struct {
-	Name         string
-	RelayEnabled bool
-	Lock         *sync.RWMutex
-	Host         *Client
-	Clients      []*Client
-	Password     string
-	MaxPlayers   int64
-	Locked       bool
-	GameID       string
-	RelayKey     string
+	Name            string
+	RelayEnabled    bool
+	Lock            *sync.RWMutex
+	Host            *Client
+	Clients         []*Client
+	Spectators      []*Client
+	Password        string
+	MaxPlayers      int64
+	MaxSpectators   int64 // 0 - spectating disabled, -1 - unlimited
+	Locked          bool
+	Closing         bool   // Set once the lobby is being closed, so that nobody else is let in
+	Phase           string // One of constants.PHASE_*
+	Backfill        bool   // Whether players may join while the match is in progress
+	GameID          string
+	RelayKey        string
+	Muted           map[string]bool // User IDs that are not permitted to chat
+	Teams           []*Team
+	Countdown       chan bool // Closed to cancel the match start countdown, nil if no countdown is running
+	MigrationPolicy string
+	Successors      []string // Instance IDs the host would like to hand the lobby to, in order of preference
+	CreatedAt       time.Time
+	LastActivity    time.Time
+	ExpiryWarned    string // Deadline ("idle" or "lifetime") the host has been warned about, empty if not warned
+	Visibility      string
+	Invites         map[string]bool         // Active invite codes
+	RequireApproval bool                    // Whether the host must approve each join
+	JoinRequests    map[string]*JoinRequest // Pending join requests, keyed by instance ID
+	WaitlistEnabled bool
+	Waitlist        []*JoinRequest       // Clients waiting for room in the lobby, in order
+	Reservations    map[string]time.Time // Player slots held for user IDs, with the time each reservation expires
+	Dedicated       bool                 // Whether the host is a dedicated host, which isn't a player and is never replaced
+	Connections     map[[2]string]string // Last reported peer-to-peer connection outcome, keyed by the pair's instance IDs in sorted order
+	Variables       map[string]*Variable // Shared lobby state, kept across host migration
+	VarClock        uint64               // Version given to the last variable change
+	MatchStartedAt  time.Time            // Origin of the match clock, zero if no match has started
+	Seed            *SeedRound           // Shared seed round in progress, nil if there is none
+	SeedStrikes     map[string]int       // Missed seed reveals, keyed by instance ID
+	ResultVotes     map[string][]byte    // Match results submitted by members (in canonical form), keyed by instance ID
+}
+
+type JoinRequest struct {
+	Client   *Client
+	Spectate bool
+	Invited  bool        // The client used an invite code, so it doesn't need approval
+	Party    []*Client   // Party members joining along with the client
+	Timer    *time.Timer // Denies the request when the host does not answer in time
+}
+
+type Variable struct {
+	Value      any
+	Version    uint64 // Lobby-wide change counter at the time of the last change
+	Owner      string // User ID of the player that created the variable
+	Permission string
+}
+
+type SeedRound struct {
+	Phase        string
+	Participants []string          // Instance IDs of the players taking part, in sorted order
+	Commits      map[string][]byte // SHA-256 hashes of the participants' secrets
+	Reveals      map[string][]byte // Verified secrets
+	Deadline     time.Time
+	Timer        *time.Timer // Ends the current phase when not everyone answers in time
+}
+
+type Team struct {
+	Name     string   `json:"name"`
+	Capacity int64    `json:"capacity"`
+	Slots    []string `json:"slots"` // Instance IDs of the assigned peers, empty if the slot is free
 }
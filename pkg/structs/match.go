@@ -0,0 +1,25 @@
+package structs
+
+import "time"
+
+// MatchResult is the recorded outcome of a match. It is stored in the database and
+// handed to Server.MatchResultHook.
+type MatchResult struct {
+	ID         string          `gorm:"primaryKey" json:"id"`
+	GameID     string          `gorm:"index" json:"game_id"`
+	Lobby      string          `json:"lobby"`
+	ReportedBy string          `json:"reported_by"` // One of constants.RESULT_BY_*
+	StartedAt  time.Time       `json:"started_at"`  // Zero if the match was not started with a countdown
+	EndedAt    time.Time       `json:"ended_at"`
+	Players    []*PlayerResult `gorm:"foreignKey:MatchID" json:"players"`
+}
+
+type PlayerResult struct {
+	ID       uint    `gorm:"primaryKey" json:"-"`
+	MatchID  string  `gorm:"index" json:"-"`
+	UserID   string  `gorm:"index" json:"user_id"`
+	Username string  `json:"username"`
+	Rank     int64   `json:"rank"` // Finishing position, starting at 1. Players may share a rank
+	Score    float64 `json:"score"`
+	Team     string  `json:"team,omitempty"`
+}
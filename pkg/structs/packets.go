@@ -1,52 +1,267 @@
 package structs
 
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
 type Packet struct {
-	Opcode  string `json:"opcode"`
-	Payload any    `json:"payload,omitempty"`
+	Opcode     string    `json:"opcode"`
+	Payload    any       `json:"payload,omitempty"`
+	ReceivedAt time.Time `json:"-"` // When the server read the packet, zero for outgoing packets
 }
 
 type CreateLobbyArgs struct {
-	Name        string `json:"name"`
-	MaxPlayers  int64  `json:"max_players"`
-	Password    string `json:"password"`
-	Locked      bool   `json:"locked"`
-	EnableRelay bool   `json:"enable_relay"`
+	Name            string     `json:"name"`
+	MaxPlayers      int64      `json:"max_players"`
+	MaxSpectators   int64      `json:"max_spectators"`
+	Password        string     `json:"password"`
+	Locked          bool       `json:"locked"`
+	EnableRelay     bool       `json:"enable_relay"`
+	Teams           []TeamArgs `json:"teams,omitempty"`
+	MigrationPolicy string     `json:"migration_policy,omitempty"`
+	Visibility      string     `json:"visibility,omitempty"`
+	RequireApproval bool       `json:"require_approval"`
+	Waitlist        bool       `json:"waitlist"`
+	Backfill        bool       `json:"backfill"`
+}
+
+type TeamArgs struct {
+	Name     string `json:"name"`
+	Capacity int64  `json:"capacity"`
+}
+
+type SwitchTeamArgs struct {
+	Team string `json:"team"`
+	Slot *int64 `json:"slot,omitempty"` // If omitted, the first free slot is used
+}
+
+type ReserveArgs struct {
+	Users    []string `json:"users,omitempty"`    // User IDs to hold slots for
+	Party    string   `json:"party,omitempty"`    // Party whose members to hold slots for
+	Duration int64    `json:"duration,omitempty"` // How long the slots are held in seconds, defaults to constants.RESERVATION_DEFAULT
+}
+
+type AssignSlotArgs struct {
+	Peer string `json:"peer"`
+	Team string `json:"team"`
+	Slot *int64 `json:"slot,omitempty"` // If omitted, the first free slot is used
 }
 
 type FindLobbyArgs struct {
-	Host             NewPeer `json:"host"`
-	MaxPlayers       int64   `json:"max_players"`
-	CurrentPlayers   uint64  `json:"current_players"`
-	CurrentlyLocked  bool    `json:"currently_locked"`
-	PasswordRequired bool    `json:"password_required"`
-	RelayEnabled     bool    `json:"relay_enabled"`
+	Name              string  `json:"name,omitempty"`
+	Host              NewPeer `json:"host"`
+	HostLatency       int64   `json:"host_latency"` // Round-trip time between the server and the host in milliseconds, zero if unknown
+	MaxPlayers        int64   `json:"max_players"`
+	CurrentPlayers    uint64  `json:"current_players"`
+	ReservedSlots     uint64  `json:"reserved_slots"`
+	MaxSpectators     int64   `json:"max_spectators"`
+	CurrentSpectators uint64  `json:"current_spectators"`
+	CurrentlyLocked   bool    `json:"currently_locked"`
+	Phase             string  `json:"phase"`
+	Backfill          bool    `json:"backfill"`
+	PasswordRequired  bool    `json:"password_required"`
+	RelayEnabled      bool    `json:"relay_enabled"`
+	Dedicated         bool    `json:"dedicated"`
+}
+
+type FindQuery struct {
+	Name   string   `json:"name"`
+	Phases []string `json:"phases,omitempty"` // Only find the lobby if it is in one of these phases
+}
+
+type LobbyQuery struct {
+	Name       string   `json:"name,omitempty"`   // Only match lobbies whose name contains this text (case insensitive)
+	HasSpace   bool     `json:"has_space"`        // Only match lobbies with room for another player
+	NoPassword bool     `json:"no_password"`      // Only match lobbies without a password
+	Unlocked   bool     `json:"unlocked"`         // Only match unlocked lobbies
+	Phases     []string `json:"phases,omitempty"` // Only match lobbies in one of these phases
+	Joinable   bool     `json:"joinable"`         // Only match lobbies whose phase lets new players join
 }
 
 type ManageLobbyArgs struct {
 	Method string `json:"method"`
 	Args   any    `json:"args"`
+	Lobby  string `json:"lobby,omitempty"` // Lobby to manage, required for dedicated hosts
+}
+
+type PartyArgs struct {
+	Method string `json:"method"`
+	Args   any    `json:"args"`
+}
+
+type PartyInvite struct {
+	Party  string  `json:"party"`
+	Leader NewPeer `json:"leader"`
+}
+
+type PartyUpdate struct {
+	ID      string    `json:"id"`
+	Leader  string    `json:"leader"`
+	Members []NewPeer `json:"members"`
 }
 
 type JoinLobbyArgs struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	Spectate bool   `json:"spectate"`
+	Invite   string `json:"invite,omitempty"` // Join using an invite code instead of a name
+}
+
+type ConnectionReport struct {
+	Peer   string `json:"peer"`   // Instance ID of the other peer
+	Status string `json:"status"` // One of constants.CONNECTION_*
+}
+
+type RelayTicket struct {
+	Relay     string `json:"relay"`      // Relay peer ID
+	Ticket    string `json:"ticket"`     // Presented in RELAY_OFFER to prove lobby membership
+	ExpiresAt int64  `json:"expires_at"` // Server time (unix milliseconds) after which the ticket can no longer be used
+}
+
+type RelaySignal struct {
+	Relay     string                     `json:"relay"`            // Relay peer ID, as sent in RELAY
+	Ticket    string                     `json:"ticket,omitempty"` // Relay ticket, required in RELAY_OFFER
+	SDP       *webrtc.SessionDescription `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+type RelayRefusal struct {
+	Reason string `json:"reason"`
+	Limit  int64  `json:"limit"`
+}
+
+func (r *RelayRefusal) Error() string {
+	return r.Reason
+}
+
+type RelayMessage struct {
+	Origin    string `json:"origin,omitempty"`    // Instance ID of the sender, set by the relay
+	Recipient string `json:"recipient,omitempty"` // Instance ID of the recipient, for P_ opcodes
+	Payload   any    `json:"payload"`
+}
+
+type VarArgs struct {
+	Key        string  `json:"key"`
+	Value      any     `json:"value,omitempty"`
+	Version    *uint64 `json:"version,omitempty"`    // Only apply the change if the variable is at this version (0 if it must not exist yet)
+	Permission string  `json:"permission,omitempty"` // Who may change the variable, one of constants.VAR_*
+	Lobby      string  `json:"lobby,omitempty"`      // Lobby of the variable, required for dedicated hosts
+}
+
+type VarWatchArgs struct {
+	Keys  []string `json:"keys,omitempty"` // Variables to include in the snapshot, or all of them if empty
+	Lobby string   `json:"lobby,omitempty"`
+}
+
+type VarEvent struct {
+	Key        string `json:"key"`
+	Value      any    `json:"value,omitempty"`
+	Version    uint64 `json:"version"`
+	Owner      string `json:"owner,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	Deleted    bool   `json:"deleted,omitempty"`
+}
+
+type SeedArgs struct {
+	Value string `json:"value,omitempty"` // Hex encoded hash (SEED_COMMIT) or secret (SEED_REVEAL)
+	Lobby string `json:"lobby,omitempty"` // Lobby of the round, required for dedicated hosts
+}
+
+type SeedRequest struct {
+	Phase        string            `json:"phase"`
+	Participants []string          `json:"participants"`
+	Commits      map[string]string `json:"commits,omitempty"` // Hex encoded commitments, sent with the reveal phase
+	Deadline     int64             `json:"deadline"`          // Server time (unix milliseconds) by which everyone must answer
+}
+
+type SeedResult struct {
+	Seed         string            `json:"seed"` // Hex encoded SHA-256 of the secrets, in participant order
+	Participants []string          `json:"participants"`
+	Reveals      map[string]string `json:"reveals"` // Hex encoded secrets, so that everyone can check the seed
+}
+
+type SeedPenalty struct {
+	Peer    string `json:"peer"`
+	Strikes int    `json:"strikes"`
+}
+
+type MatchResultArgs struct {
+	Players []PlayerResultArgs `json:"players"`
+	Lobby   string             `json:"lobby,omitempty"` // Lobby of the match, required for dedicated hosts
+}
+
+type PlayerResultArgs struct {
+	Peer  string  `json:"peer"` // Instance ID of the player
+	Rank  int64   `json:"rank"` // Finishing position, starting at 1
+	Score float64 `json:"score"`
+	Team  string  `json:"team,omitempty"`
+}
+
+type ChatArgs struct {
+	Recipient string `json:"recipient,omitempty"`
+	Message   string `json:"message"`
+}
+
+type ChatMessage struct {
+	Sender  NewPeer `json:"sender"`
+	Message string  `json:"message"`
+}
+
+type ReadyState struct {
+	InstanceID string `json:"instance_id"`
+	Ready      bool   `json:"ready"`
+}
+
+type StartArgs struct {
+	Countdown int64  `json:"countdown"`       // Countdown length in seconds, or 0 for the default
+	Force     bool   `json:"force"`           // Start even if not every player is ready
+	Cancel    bool   `json:"cancel"`          // Cancel a running countdown instead of starting one
+	Lobby     string `json:"lobby,omitempty"` // Lobby to start, required for dedicated hosts
+}
+
+type CountdownEvent struct {
+	Remaining int64 `json:"remaining"`  // Seconds left until the match starts
+	StartTime int64 `json:"start_time"` // Server time (unix milliseconds) at which the match starts
+}
+
+type MatchStart struct {
+	StartTime  int64   `json:"start_time"`  // Server time (unix milliseconds) at which every peer should begin
+	ServerTime float64 `json:"server_time"` // Server time (unix milliseconds) at which this message was sent
+}
+
+type TimeSync struct {
+	T0        float64 `json:"t0"`                   // Client time at which the request was sent, echoed back as is
+	T1        float64 `json:"t1"`                   // Server time (unix milliseconds) at which the request was received
+	T2        float64 `json:"t2"`                   // Server time (unix milliseconds) at which the reply was sent
+	MatchTime float64 `json:"match_time,omitempty"` // Milliseconds on the lobby's match clock at T2, if a match has started
+}
+
+type LobbyExpiry struct {
+	Reason    string `json:"reason"`     // "idle" or "lifetime"
+	ExpiresAt int64  `json:"expires_at"` // Server time (unix milliseconds) at which the lobby will be closed
 }
 
 type InitArgs struct {
 	Username  string `json:"username"`
 	Token     string `json:"token"`
 	PublicKey string `json:"pubkey,omitempty"`
+	APIKey    string `json:"api_key,omitempty"` // Registers the client as a dedicated host of the game
 }
 
 type InitResponse struct {
 	InstanceID string `json:"instance_id"`
 	UserID     string `json:"user_id"`
 	Username   string `json:"username"`
+	Dedicated  bool   `json:"dedicated,omitempty"`
 }
 
 type NewPeer struct {
-	InstanceID string `json:"instance_id"`
-	UserID     string `json:"user_id"`
-	Username   string `json:"username"`
-	PublicKey  string `json:"pubkey,omitempty"`
+	InstanceID string   `json:"instance_id"`
+	UserID     string   `json:"user_id"`
+	Username   string   `json:"username"`
+	PublicKey  string   `json:"pubkey,omitempty"`
+	Spectator  bool     `json:"spectator,omitempty"`
+	Party      []string `json:"party,omitempty"` // Instance IDs of the party members joining along with the peer
 }
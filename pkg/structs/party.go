@@ -0,0 +1,8 @@
+package structs
+
+type Party struct {
+	ID      string
+	Leader  *Client
+	Members []*Client       // Including the leader, in join order
+	Invites map[string]bool // Instance IDs that have been invited and haven't answered yet
+}
@@ -1,12 +1,34 @@
 package structs
 
 import (
-	peer "github.com/muka/peerjs-go"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v3"
 )
 
 type Relay struct {
-	Handler   *peer.Peer
-	Id        string
-	Close     chan bool
-	CloseDone chan bool
+	Id          string
+	GameID      string
+	Lobby       string
+	Lock        *sync.Mutex
+	Peers       map[string]*RelayPeer // Peers connected to the relay, keyed by instance ID
+	MaxPeers    int
+	ByteRate    int64     // Bytes the relay may forward per second
+	Window      time.Time // Start of the current byte rate window
+	WindowBytes int64     // Bytes forwarded in the current byte rate window
+	BytesIn     atomic.Int64
+	BytesOut    atomic.Int64
+	Dropped     atomic.Int64
+	Closed      bool // Set once the relay has been closed, after which no peers may connect
+	Close       chan bool
+	CloseDone   chan bool
+}
+
+type RelayPeer struct {
+	Client  *Client
+	Conn    *webrtc.PeerConnection
+	Channel *webrtc.DataChannel // nil until the peer's data channel has opened
+	Ticket  string              // Relay ticket the peer connected with
 }
@@ -3,6 +3,7 @@ package structs
 import (
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/cloudlink-omega/accounts/pkg/authorization"
 	backend "github.com/cloudlink-omega/backend/pkg/database"
@@ -18,8 +19,17 @@ type Server struct {
 	Lobbies                  map[string]map[string]*Lobby
 	GlobalPeerIDs            map[string][]string
 	UninitializedPeers       map[string][]*Client
+	Subscribers              map[string][]*Client              // Clients subscribed to lobby list updates, keyed by game ID
+	Parties                  map[string]map[string]*Party      // Parties keyed by game ID, then by party ID
+	ChatHistory              map[string]map[string][]time.Time // Timestamps of recently sent chat messages keyed by game ID, then by user ID, used for rate limiting
 	DB                       *gorm.DB
 	Authorization            *authorization.Auth
 	GamesDB                  *backend.Database
 	BypassDB                 bool
+	PingInterval             time.Duration            // How often clients are pinged. Zero disables server pings.
+	GameSettings             map[string]*GameSettings // Optional per-game settings, keyed by game ID
+	ReadTimeout              time.Duration            // How long a client may go without sending anything (including pongs) before it is disconnected. Zero disables the timeout.
+	MaxRelays                int                      // Maximum number of relays across all games.
+	RelaySecret              []byte                   // Signs relay tickets.
+	MatchResultHook          func(*MatchResult)       // Called with every recorded match result, for example to update rankings.
 }
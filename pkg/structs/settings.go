@@ -0,0 +1,14 @@
+package structs
+
+import "time"
+
+// GameSettings holds optional per-game behaviour. A zero value disables the setting.
+type GameSettings struct {
+	LobbyIdleExpiry  time.Duration // Close lobbies that have seen no joins or activity for this long.
+	LobbyMaxLifetime time.Duration // Close lobbies that have existed for this long.
+	ExpiryWarning    time.Duration // Warn the host this long before a lobby is closed. Defaults to constants.LOBBY_EXPIRY_WARNING.
+	HostKey          string        // API key that dedicated hosts of the game authenticate with.
+	MaxRelays        int           // Maximum number of relays for the game. Defaults to constants.MAX_RELAYS_PER_GAME.
+	MaxRelayPeers    int           // Maximum number of peers connected to a single relay. Defaults to constants.MAX_RELAY_PEERS.
+	RelayByteRate    int64         // Number of bytes a single relay may forward per second. Defaults to constants.RELAY_BYTES_PER_SEC.
+}
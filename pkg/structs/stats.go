@@ -0,0 +1,21 @@
+package structs
+
+type RelayStats struct {
+	Relay    string `json:"relay"`
+	Lobby    string `json:"lobby"`
+	Peers    int    `json:"peers"`
+	BytesIn  int64  `json:"bytes_in"`  // Bytes received from peers
+	BytesOut int64  `json:"bytes_out"` // Bytes forwarded to peers
+	Dropped  int64  `json:"dropped"`   // Messages dropped for exceeding the byte rate
+}
+
+type ClientStats struct {
+	InstanceID string `json:"instance_id"`
+	UserID     string `json:"user_id"`
+	Username   string `json:"username"`
+	State      int8   `json:"state"`
+	Lobby      string `json:"lobby,omitempty"`
+	RTT        int64  `json:"rtt"`    // Smoothed round-trip time measured by the server in milliseconds, zero if unknown
+	Jitter     int64  `json:"jitter"` // Smoothed round-trip time variation in milliseconds
+	Reported   int64  `json:"reported_latency"`
+}